@@ -1,86 +1,239 @@
 package main
 
 import (
-	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/audit"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/auth"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/common/config"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/httpclient"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/common/rng"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/tenant"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/games/birdsparty"
 )
 
 func main() {
 	// Load configuration
-	prodCfg, testCfg := config.LoadAll()
-
-	fmt.Println("Production Configuration:", prodCfg)
-	fmt.Println("Test Configuration:", testCfg)
+	cfg := config.Load()
 
 	// Set up logging
-	logFile, err := os.OpenFile(prodCfg.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logFile, err := os.OpenFile(cfg.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		log.Fatalf("Error opening log file: %v", err)
 	}
 	defer logFile.Close()
 	log.SetOutput(logFile)
 
-	// Create shared clients
-	rngClient := rng.NewClient(prodCfg.RNGServiceURL)
-	settingsClient := settings.NewClient(prodCfg.SettingsServiceURL)
-	
-	// Create test clients
-	rngTestClient := rng.NewClient(testCfg.RNGServiceURL)
-	settingsTestClient := settings.NewClient(testCfg.SettingsServiceURL)
+	// Shared HTTP client so every outbound call has timeouts and pooled connections
+	httpClient := httpclient.New(httpclient.Config{
+		Timeout:     cfg.HTTPTimeout,
+		DialTimeout: cfg.HTTPDialTimeout,
+	})
+
+	// Refuse to start with symbol weights the game cannot play
+	if err := birdsparty.ValidateSymbolWeightTables(); err != nil {
+		log.Fatalf("Invalid symbol weight tables: %v", err)
+	}
+	if err := birdsparty.DefaultPaytable.Validate(); err != nil {
+		log.Fatalf("Invalid paytable: %v", err)
+	}
+
+	// Load the tenant registry and create the clients for each environment
+	tenants, err := tenant.Load(cfg.TenantsFile)
+	if err != nil {
+		log.Fatalf("Error loading tenant registry: %v", err)
+	}
+	environments := make(map[string]birdsparty.Environment)
+	for _, env := range tenants.Environments() {
+		environments[env.Name] = birdsparty.Environment{
+			RNG:      newOutcomeProvider(cfg, env.RNGServiceURL, httpClient),
+			Settings: newSettingsProvider(cfg, env.SettingsServiceURL, httpClient),
+		}
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		ErrorHandler: customErrorHandler,
 	})
 
-	// Add middleware
+	// Add middleware; the request ID is the correlation ID returned in error responses
+	app.Use(requestid.New())
 	app.Use(recover.New())
 	app.Use(logger.New(logger.Config{
-		Format:     "[${time}] ${status} - ${method} ${path}\n",
+		Format:     "[${time}] ${status} - ${method} ${path} ${respHeader:X-Request-ID}\n",
 		TimeFormat: "2006-01-02 15:04:05",
 		Output:     logFile,
 	}))
 
 	// Register routes for Birds Party
-	birdsPartyRoutes := birdsparty.NewRouteGroup(rngClient, settingsClient, rngTestClient, settingsTestClient)
+	birdsPartyRoutes := birdsparty.NewRouteGroup(tenants, environments, session.NewIssuer(cfg.SessionSecret, cfg.SessionTTL))
+	birdsPartyRoutes.LaunchURL = cfg.GameLaunchURL
+	birdsPartyRoutes.MaxWinMultiplier = cfg.MaxWinMultiplier
+	birdsPartyRoutes.WebSocketKeepAlive = cfg.WebSocketKeepAlive
+	birdsPartyRoutes.AutoplayMaxRounds = cfg.AutoplayMaxRounds
+	birdsPartyRoutes.Responsible = responsible.NewTracker()
+	birdsPartyRoutes.FreeRounds = birdsparty.NewFreeRounds()
+	if cfg.ReelStripsFile != "" {
+		reelStrips, err := birdsparty.LoadReelStrips(cfg.ReelStripsFile)
+		if err != nil {
+			log.Fatalf("Error loading reel strips: %v", err)
+		}
+		birdsPartyRoutes.ReelStrips = reelStrips
+		log.Printf("Loaded reel strips for %d math variants from %s", len(reelStrips), cfg.ReelStripsFile)
+	}
+	if cfg.PaytableFile != "" {
+		paytable, err := birdsparty.LoadPaytable(cfg.PaytableFile)
+		if err != nil {
+			log.Fatalf("Error loading paytable: %v", err)
+		}
+		birdsPartyRoutes.Paytable = paytable
+		log.Printf("Loaded paytable from %s", cfg.PaytableFile)
+	}
+	if cfg.JackpotsFile != "" {
+		pools, err := birdsparty.LoadJackpots(cfg.JackpotsFile)
+		if err != nil {
+			log.Fatalf("Error loading jackpots: %v", err)
+		}
+		birdsPartyRoutes.Jackpots = birdsparty.NewJackpots(pools)
+		log.Printf("Loaded %d jackpot pools from %s", len(pools), cfg.JackpotsFile)
+	}
+	if cfg.TournamentsFile != "" {
+		tournaments, err := birdsparty.LoadTournaments(cfg.TournamentsFile)
+		if err != nil {
+			log.Fatalf("Error loading tournaments: %v", err)
+		}
+		for _, t := range tournaments {
+			if _, ok := tenants.Lookup(t.ClientID); !ok {
+				log.Fatalf("Tournament %s is for unknown client %s", t.ID, t.ClientID)
+			}
+		}
+		birdsPartyRoutes.Tournaments = birdsparty.NewTournaments(tournaments, birdsparty.LogPrizes{})
+		go birdsPartyRoutes.Tournaments.RunSettlement(time.Minute)
+		log.Printf("Loaded %d tournaments from %s", len(tournaments), cfg.TournamentsFile)
+	}
+	if cfg.MissionsFile != "" {
+		missions, err := birdsparty.LoadMissions(cfg.MissionsFile)
+		if err != nil {
+			log.Fatalf("Error loading missions: %v", err)
+		}
+		birdsPartyRoutes.Missions, err = birdsparty.NewMissions(missions, cfg.MissionsProgressFile)
+		if err != nil {
+			log.Fatalf("Error loading mission progress: %v", err)
+		}
+		if cfg.MissionsProgressFile != "" {
+			go birdsPartyRoutes.Missions.RunPersistence(cfg.MissionsSaveInterval)
+		}
+		log.Printf("Loaded %d missions from %s", len(missions), cfg.MissionsFile)
+	}
+	birdsPartyRoutes.RoundRecovery = cfg.RoundRecovery
+	birdsPartyRoutes.RoundSettleTimeout = cfg.RoundSettleTimeout
+	if cfg.AuditLogFile != "" {
+		auditFile, err := os.OpenFile(cfg.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Error opening audit log file: %v", err)
+		}
+		defer auditFile.Close()
+		birdsPartyRoutes.Audit = audit.NewLogger(auditFile)
+	}
+	birdsPartyRoutes.Authenticate = auth.New(auth.Config{
+		Secrets:      tenants,
+		ReplayWindow: cfg.AuthReplayWindow,
+		TestKey:      cfg.AuthTestKey,
+	})
 	birdsPartyRoutes.Register(app)
+	if cfg.RoundSettleTimeout > 0 {
+		go birdsPartyRoutes.RunRoundRecovery(time.Minute)
+	}
 
 	// Add a simple status endpoint
 	app.Get("/status", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
-			"status": "ok",
-			"game":   "birdsparty",
+			"status":        "ok",
+			"game":          "birdsparty",
+			"settingsCache": cacheStats(environments),
 		})
 	})
 
 	// Start the server
-	port := prodCfg.ServerPort
+	port := cfg.ServerPort
 	log.Printf("Starting Birds Party server on port %s", port)
 	log.Fatal(app.Listen(":" + port))
 }
 
-// Custom error handler
-func customErrorHandler(c *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
+// newOutcomeProvider creates the RNG client, or a local deterministic provider when RNG_MODE asks for one
+func newOutcomeProvider(cfg config.Config, serviceURL string, httpClient *http.Client) birdsparty.OutcomeProvider {
+	switch cfg.RNGMode {
+	case "fixed":
+		log.Printf("Using fixed RNG outcome %q", cfg.RNGFixedOutcome)
+		return rng.NewFixedOutcome(cfg.RNGFixedOutcome)
+	case "scripted":
+		log.Printf("Using scripted RNG outcomes %q", cfg.RNGScript)
+		return rng.NewScriptedOutcome(strings.Split(cfg.RNGScript, ","), true)
+	case "probability":
+		log.Printf("Using probability RNG with win probability %.3f and seed %d", cfg.RNGWinProbability, cfg.RNGSeed)
+		return rng.NewProbabilityOutcome(cfg.RNGWinProbability, cfg.RNGSeed)
+	default:
+		return rng.NewClient(serviceURL, rngOptions(cfg, httpClient))
+	}
+}
+
+// newSettingsProvider creates the cached settings client, or static settings when SETTINGS_MODE is "static"
+func newSettingsProvider(cfg config.Config, serviceURL string, httpClient *http.Client) birdsparty.SettingsProvider {
+	if cfg.SettingsMode == "static" {
+		provider, err := settings.NewStaticProvider(cfg.StaticRTP, cfg.StaticBets, cfg.StaticWins)
+		if err != nil {
+			log.Fatalf("Invalid static settings: %v", err)
+		}
+		log.Printf("Using static game settings: %+v", provider.Settings)
+		return provider
+	}
+	return settings.NewCachedClient(settings.NewClient(serviceURL, httpClient), settingsCacheOptions(cfg))
+}
 
-	// Handle common errors with appropriate status codes
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
+// cacheStats returns the settings cache counters per environment, skipping providers without a cache
+func cacheStats(environments map[string]birdsparty.Environment) map[string]settings.CacheStats {
+	stats := make(map[string]settings.CacheStats)
+	for name, env := range environments {
+		if cached, ok := env.Settings.(*settings.CachedClient); ok {
+			stats[name] = cached.Stats()
+		}
 	}
+	return stats
+}
 
-	log.Printf("Request failed: %v", err)
-	return c.Status(code).JSON(fiber.Map{
-		"status":  "error",
-		"message": err.Error(),
-	})
-}
\ No newline at end of file
+// rngOptions builds the RNG client resilience options from configuration
+func rngOptions(cfg config.Config, httpClient *http.Client) rng.Options {
+	return rng.Options{
+		HTTPClient:       httpClient,
+		MaxRetries:       cfg.RNGMaxRetries,
+		BreakerThreshold: cfg.RNGBreakerThreshold,
+		BreakerCooldown:  cfg.RNGBreakerCooldown,
+		Fallback:         rng.FallbackPolicy(cfg.RNGFallbackPolicy),
+	}
+}
+
+// settingsCacheOptions builds the RTP cache options from configuration
+func settingsCacheOptions(cfg config.Config) settings.CacheOptions {
+	return settings.CacheOptions{
+		TTL:      cfg.SettingsCacheTTL,
+		StaleTTL: cfg.SettingsCacheStaleTTL,
+	}
+}
+
+// Custom error handler, for errors no handler has written a response for
+func customErrorHandler(c *fiber.Ctx, err error) error {
+	return apierror.Write(c, err)
+}
@@ -0,0 +1,230 @@
+// Package apierror defines the error codes returned by the API and the error response envelope.
+// Every error response carries a stable code, the HTTP status it maps to, whether the same
+// request may succeed if retried, and the request's correlation ID.
+package apierror
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Code is a stable, machine-readable error code; clients should branch on it, never on the message
+type Code string
+
+// Request errors
+const (
+	InvalidRequest       Code = "INVALID_REQUEST"
+	InvalidBet           Code = "INVALID_BET"
+	UnsupportedCurrency  Code = "UNSUPPORTED_CURRENCY"
+	GridMismatch         Code = "GRID_MISMATCH"
+	InvalidGameState     Code = "INVALID_GAME_STATE"
+	RoundEnded           Code = "ROUND_ENDED"
+	RoundInProgress      Code = "ROUND_IN_PROGRESS"
+	RoundBusy            Code = "ROUND_BUSY"
+	StepOutOfOrder       Code = "STEP_OUT_OF_ORDER"
+	DuplicateBet         Code = "DUPLICATE_BET"
+	FreeRoundUnavailable Code = "FREE_ROUND_UNAVAILABLE"
+	NotFound             Code = "NOT_FOUND"
+	MethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	UpgradeRequired      Code = "UPGRADE_REQUIRED"
+)
+
+// Authentication errors
+const (
+	SessionRequired      Code = "SESSION_REQUIRED"
+	SessionInvalid       Code = "SESSION_INVALID"
+	SessionExpired       Code = "SESSION_EXPIRED"
+	AuthenticationFailed Code = "AUTHENTICATION_FAILED"
+	InvalidAPIKey        Code = "INVALID_API_KEY"
+	UnknownClient        Code = "UNKNOWN_CLIENT"
+)
+
+// Responsible-gaming blocks; details carry what the client needs to render them
+const (
+	SelfExcluded         Code = "SELF_EXCLUDED"
+	LossLimitReached     Code = "LOSS_LIMIT_REACHED"
+	WagerLimitReached    Code = "WAGER_LIMIT_REACHED"
+	DepositLimitReached  Code = "DEPOSIT_LIMIT_REACHED"
+	SessionTimeLimit     Code = "SESSION_TIME_LIMIT"
+	RealityCheckRequired Code = "REALITY_CHECK_REQUIRED"
+)
+
+// Server and dependency errors
+const (
+	RNGUnavailable         Code = "RNG_UNAVAILABLE"
+	SettingsUnavailable    Code = "SETTINGS_UNAVAILABLE"
+	EnvironmentUnavailable Code = "ENVIRONMENT_UNAVAILABLE"
+	FeatureDisabled        Code = "FEATURE_DISABLED"
+	Internal               Code = "INTERNAL_ERROR"
+)
+
+// definition is how a code is reported
+type definition struct {
+	status    int
+	retryable bool
+	message   string // Used when an error is created without a message
+}
+
+// catalogue maps every code to its HTTP status, retryability and default message
+var catalogue = map[Code]definition{
+	InvalidRequest:       {fiber.StatusBadRequest, false, "Invalid request body"},
+	InvalidBet:           {fiber.StatusBadRequest, false, "Invalid bet amount"},
+	UnsupportedCurrency:  {fiber.StatusBadRequest, false, "Unsupported currency"},
+	GridMismatch:         {fiber.StatusBadRequest, false, "Invalid grid dimensions"},
+	InvalidGameState:     {fiber.StatusBadRequest, false, "Inconsistent game state"},
+	RoundEnded:           {fiber.StatusBadRequest, false, "Maximum win reached, the round has ended"},
+	RoundInProgress:      {fiber.StatusConflict, false, "Finish the round in progress before starting a new one"},
+	RoundBusy:            {fiber.StatusConflict, true, "A step of this round is already being played"},
+	StepOutOfOrder:       {fiber.StatusConflict, false, "This step is not allowed at this point of the round"},
+	DuplicateBet:         {fiber.StatusConflict, false, "bet_id has already been played"},
+	FreeRoundUnavailable: {fiber.StatusConflict, false, "The free round grant has no rounds left to play"},
+	NotFound:             {fiber.StatusNotFound, false, "Not found"},
+	MethodNotAllowed:     {fiber.StatusMethodNotAllowed, false, "Method not allowed"},
+	UpgradeRequired:      {fiber.StatusUpgradeRequired, false, "WebSocket upgrade required"},
+
+	SessionRequired:      {fiber.StatusUnauthorized, false, "Session token is required"},
+	SessionInvalid:       {fiber.StatusUnauthorized, false, "Invalid session token"},
+	SessionExpired:       {fiber.StatusUnauthorized, false, "Session expired"},
+	AuthenticationFailed: {fiber.StatusUnauthorized, false, "Authentication failed"},
+	InvalidAPIKey:        {fiber.StatusUnauthorized, false, "Invalid API key"},
+	UnknownClient:        {fiber.StatusForbidden, false, "Unknown client"},
+
+	SelfExcluded:         {fiber.StatusForbidden, false, "Player is self-excluded"},
+	LossLimitReached:     {fiber.StatusForbidden, false, "Loss limit reached"},
+	WagerLimitReached:    {fiber.StatusForbidden, false, "Wager limit reached"},
+	DepositLimitReached:  {fiber.StatusForbidden, false, "Deposit limit reached"},
+	SessionTimeLimit:     {fiber.StatusForbidden, false, "Session time limit reached"},
+	RealityCheckRequired: {fiber.StatusForbidden, false, "Please review your session before continuing"},
+
+	RNGUnavailable:         {fiber.StatusServiceUnavailable, true, "Failed to determine outcome"},
+	SettingsUnavailable:    {fiber.StatusServiceUnavailable, true, "Failed to retrieve game settings"},
+	EnvironmentUnavailable: {fiber.StatusInternalServerError, false, "Client environment is not available"},
+	FeatureDisabled:        {fiber.StatusServiceUnavailable, false, "Feature is not enabled"},
+	Internal:               {fiber.StatusInternalServerError, false, "Internal server error"},
+}
+
+// Error is an API error with a catalogued code
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]interface{}
+	// Err is the underlying cause; it is logged but never sent to the client
+	Err error
+}
+
+// New creates an error; an empty message uses the code's default message
+func New(code Code, message string) *Error {
+	if message == "" {
+		message = catalogue[code].message
+	}
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with the code's default message around an underlying cause
+func Wrap(code Code, err error) *Error {
+	e := New(code, "")
+	e.Err = err
+	return e
+}
+
+// WithDetails attaches details the client can render and returns the error
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status the code maps to
+func (e *Error) Status() int {
+	if def, ok := catalogue[e.Code]; ok {
+		return def.status
+	}
+	return fiber.StatusInternalServerError
+}
+
+// Retryable reports whether the same request may succeed if sent again
+func (e *Error) Retryable() bool {
+	return catalogue[e.Code].retryable
+}
+
+// From returns err as an *Error. Fiber errors are mapped by status; anything else is an internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		switch {
+		case fiberErr.Code == fiber.StatusNotFound:
+			return New(NotFound, fiberErr.Message)
+		case fiberErr.Code == fiber.StatusMethodNotAllowed:
+			return New(MethodNotAllowed, fiberErr.Message)
+		case fiberErr.Code >= 400 && fiberErr.Code < 500:
+			return New(InvalidRequest, fiberErr.Message)
+		}
+	}
+	return Wrap(Internal, err)
+}
+
+// Is reports whether err is an *Error with one of the given codes
+func Is(err error, codes ...Code) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Response is the error envelope returned by every endpoint and WebSocket error message
+type Response struct {
+	Status        string                 `json:"status"`
+	Code          Code                   `json:"code"`
+	Message       string                 `json:"message"`
+	Retryable     bool                   `json:"retryable"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+}
+
+// Body maps an error to its HTTP status and envelope
+func Body(err error, correlationID string) (int, Response) {
+	apiErr := From(err)
+	return apiErr.Status(), Response{
+		Status:        "error",
+		Code:          apiErr.Code,
+		Message:       apiErr.Message,
+		Retryable:     apiErr.Retryable(),
+		CorrelationID: correlationID,
+		Details:       apiErr.Details,
+	}
+}
+
+// Write logs the error with the request's correlation ID and writes the envelope
+func Write(c *fiber.Ctx, err error) error {
+	id := CorrelationID(c)
+	status, body := Body(err, id)
+	log.Printf("[%s] %s %s failed with %d: %v", id, c.Method(), c.Path(), status, err)
+	return c.Status(status).JSON(body)
+}
+
+// CorrelationID returns the request ID assigned by the requestid middleware, or "" if it is not installed
+func CorrelationID(c *fiber.Ctx) string {
+	return c.GetRespHeader(fiber.HeaderXRequestID)
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+)
+
+// Record is one audited game step. Money fields are integer minor units of Currency.
+type Record struct {
+	Time          time.Time    `json:"time"`
+	Event         string       `json:"event"` // "spin", "cascade" or "stageCleared"
+	SessionID     string       `json:"session_id"`
+	ClientID      string       `json:"client_id"`
+	PlayerID      string       `json:"player_id"`
+	GameID        string       `json:"game_id"`
+	BetID         string       `json:"bet_id"`
+	Currency      string       `json:"currency"`
+	Bet           money.Amount `json:"bet"`
+	Cost          money.Amount `json:"cost"`
+	Win           money.Amount `json:"win"`
+	RoundWin      money.Amount `json:"round_win"`
+	Level         int          `json:"level"`
+	GameMode      string       `json:"game_mode"`
+	MaxWinReached bool         `json:"max_win_reached,omitempty"`
+	// JackpotContribution is what the step added to the jackpot pools; contributions keep fractions of a minor unit
+	JackpotContribution float64      `json:"jackpot_contribution,omitempty"`
+	JackpotWin          money.Amount `json:"jackpot_win,omitempty"` // Paid on top of Win
+	Jackpots            []string     `json:"jackpots,omitempty"`    // Pools won
+	// FreeRound is the operator's free round grant the step was played from; its win is bonus winnings
+	FreeRound string `json:"free_round,omitempty"`
+	// Replay is what the game needs to re-execute the step exactly, such as birdsparty.StepReplay
+	Replay interface{} `json:"replay,omitempty"`
+}
+
+// Logger writes audit records as JSON lines
+type Logger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewLogger creates a logger writing to w
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{enc: json.NewEncoder(w)}
+}
+
+// Log writes a record. A nil logger discards it.
+func (l *Logger) Log(r Record) {
+	if l == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(r); err != nil {
+		log.Printf("Failed to write audit record for bet %s: %v", r.BetID, err)
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Request headers used by signed operator requests
+const (
+	HeaderClientID  = "X-Client-ID"
+	HeaderTimestamp = "X-Timestamp" // Unix seconds
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature" // Hex HMAC-SHA256, see Sign
+	HeaderTestKey   = "X-Test-Key"
+)
+
+// localsClientID is the fiber.Ctx locals key holding the authenticated client ID
+const localsClientID = "auth.clientID"
+
+// SecretStore returns the signing secret shared with an operator
+type SecretStore interface {
+	Secret(clientID string) (string, bool)
+}
+
+// Config configures the HMAC middleware
+type Config struct {
+	Secrets SecretStore
+	// ReplayWindow is the maximum clock skew accepted for X-Timestamp; nonces are remembered for twice this long
+	ReplayWindow time.Duration
+	// TestKey enables local development mode: a request whose X-Test-Key equals it skips signature checks.
+	// Leave empty in every deployed environment.
+	TestKey string
+	// Nonces remembers nonces already used; defaults to an in-memory store
+	Nonces NonceStore
+}
+
+// Sign computes the request signature: hex(HMAC-SHA256(secret, timestamp \n nonce \n method \n path \n body))
+func Sign(secret, timestamp, nonce, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + method + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// New creates a middleware that rejects unsigned, mis-signed, stale or replayed requests
+func New(cfg Config) fiber.Handler {
+	if cfg.ReplayWindow <= 0 {
+		cfg.ReplayWindow = 5 * time.Minute
+	}
+	if cfg.Nonces == nil {
+		cfg.Nonces = NewMemoryNonceStore()
+	}
+	if cfg.TestKey != "" {
+		log.Printf("WARNING: HMAC authentication test-key mode is enabled")
+	}
+
+	return func(c *fiber.Ctx) error {
+		clientID := c.Get(HeaderClientID)
+
+		if cfg.TestKey != "" && subtle.ConstantTimeCompare([]byte(c.Get(HeaderTestKey)), []byte(cfg.TestKey)) == 1 {
+			c.Locals(localsClientID, clientID)
+			return c.Next()
+		}
+
+		timestamp := c.Get(HeaderTimestamp)
+		nonce := c.Get(HeaderNonce)
+		signature := c.Get(HeaderSignature)
+		if clientID == "" || timestamp == "" || nonce == "" || signature == "" {
+			return reject(c, "Missing authentication headers")
+		}
+
+		secret, ok := cfg.Secrets.Secret(clientID)
+		if !ok {
+			return reject(c, "Unknown client")
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return reject(c, "Invalid timestamp")
+		}
+		skew := time.Since(time.Unix(seconds, 0))
+		if skew > cfg.ReplayWindow || skew < -cfg.ReplayWindow {
+			log.Printf("Rejected stale request from %s: clock skew %v", clientID, skew)
+			return reject(c, "Request timestamp outside the allowed window")
+		}
+
+		expected := Sign(secret, timestamp, nonce, c.Method(), c.Path(), c.Body())
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			log.Printf("Rejected request from %s: signature mismatch", clientID)
+			return reject(c, "Invalid signature")
+		}
+
+		// Only remember the nonce once the signature proves the caller knows the secret
+		if !cfg.Nonces.Remember(clientID+":"+nonce, 2*cfg.ReplayWindow) {
+			log.Printf("Rejected replayed request from %s: nonce %s already used", clientID, nonce)
+			return reject(c, "Nonce already used")
+		}
+
+		c.Locals(localsClientID, clientID)
+		return c.Next()
+	}
+}
+
+// ClientID returns the client ID authenticated by the middleware, or "" if the request was not authenticated
+func ClientID(c *fiber.Ctx) string {
+	clientID, _ := c.Locals(localsClientID).(string)
+	return clientID
+}
+
+func reject(c *fiber.Ctx, message string) error {
+	return apierror.Write(c, apierror.New(apierror.AuthenticationFailed, message))
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceStore remembers nonces for the replay window
+type NonceStore interface {
+	// Remember records the nonce and reports whether it was new
+	Remember(nonce string, ttl time.Duration) bool
+}
+
+// MemoryNonceStore is an in-process NonceStore; use one per server instance
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryNonceStore creates an empty in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		seen:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Remember records the nonce until ttl elapses and reports whether it was new
+func (s *MemoryNonceStore) Remember(nonce string, ttl time.Duration) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expiry, ok := s.seen[nonce]; ok && now.Before(expiry) {
+		return false
+	}
+	s.seen[nonce] = now.Add(ttl)
+
+	// Drop expired nonces at most once per ttl
+	if now.Sub(s.lastSweep) >= ttl {
+		for n, expiry := range s.seen {
+			if !now.Before(expiry) {
+				delete(s.seen, n)
+			}
+		}
+		s.lastSweep = now
+	}
+	return true
+}
@@ -3,16 +3,81 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration from environment
 type Config struct {
-	RNGServiceURL      string
-	SettingsServiceURL string
-	ServerPort         string
-	LogFile            string
+	ServerPort   string
+	LogFile      string
+	AuditLogFile string // JSON-lines audit trail of every game step; empty disables it
+
+	// TenantsFile is the JSON registry mapping client IDs to environments (RNG and settings URLs)
+	TenantsFile string
+
+	// HMAC request authentication
+	AuthReplayWindow time.Duration
+	AuthTestKey      string // Enables test-key mode for local development; never set in deployed environments
+
+	// Player sessions
+	SessionSecret string
+	SessionTTL    time.Duration
+	GameLaunchURL string // Game client URL the session token is appended to
+
+	// WebSocket game channel
+	WebSocketKeepAlive time.Duration
+
+	// AutoplayMaxRounds caps the rounds of one autoplay request
+	AutoplayMaxRounds int
+
+	// ReelStripsFile holds reel strips by math variant; empty means every tenant draws from the symbol weight tables
+	ReelStripsFile string
+	// PaytableFile holds the size-band paytable and its modifiers; empty uses the built-in paytable
+	PaytableFile string
+	// JackpotsFile holds the progressive jackpot pools; empty disables jackpots
+	JackpotsFile string
+	// TournamentsFile holds the tournament definitions; empty disables tournaments
+	TournamentsFile string
+	// MissionsFile holds the mission definitions; empty disables missions
+	MissionsFile string
+	// MissionsProgressFile is where mission progress is saved every MissionsSaveInterval; empty keeps it in memory only
+	MissionsProgressFile string
+	MissionsSaveInterval time.Duration
+
+	// Unfinished round recovery
+	RoundRecovery      string        // "resume" or "complete"
+	RoundSettleTimeout time.Duration // Unfinished rounds are completed by the server after this; 0 disables it
+
+	// Outbound HTTP transport shared by the RNG and settings clients
+	HTTPTimeout     time.Duration
+	HTTPDialTimeout time.Duration
+
+	// RNG client resilience
+	RNGMaxRetries       uint64
+	RNGBreakerThreshold int
+	RNGBreakerCooldown  time.Duration
+	RNGFallbackPolicy   string // "error" or "loss"
+
+	// Outcome and settings providers: "http" uses the remote services, other modes are local and deterministic
+	RNGMode           string  // "http", "fixed", "scripted" or "probability"
+	RNGFixedOutcome   string  // Outcome returned in "fixed" mode
+	RNGScript         string  // Comma-separated outcomes replayed in "scripted" mode
+	RNGWinProbability float64 // Win probability in "probability" mode
+	RNGSeed           int64   // Seed for "probability" mode
+	SettingsMode      string  // "http" or "static"
+	StaticRTP         string  // Settings served in "static" mode, in the settings service formats
+	StaticBets        string
+	StaticWins        string
+
+	// Default round win cap as a multiple of the bet, used when the operator sets none (0 disables)
+	MaxWinMultiplier float64
+
+	// RTP settings cache
+	SettingsCacheTTL      time.Duration
+	SettingsCacheStaleTTL time.Duration
 }
 
 // Load loads configuration from environment variables
@@ -23,10 +88,44 @@ func Load() Config {
 	}
 
 	return Config{
-		RNGServiceURL:      getEnv("RNG_API_URL", "http://159.89.235.166:17003/api/proxy/rng/1"),
-		SettingsServiceURL: getEnv("SETTINGS_API_URL", "https://t3.ibibe.africa/get-game-settings"),
-		ServerPort:         getEnv("PORT", "11400"),
-		LogFile:            getEnv("LOG_FILE", "app.log"),
+		ServerPort:            getEnv("PORT", "11400"),
+		LogFile:               getEnv("LOG_FILE", "app.log"),
+		AuditLogFile:          getEnv("AUDIT_LOG_FILE", "audit.log"),
+		TenantsFile:           getEnv("TENANTS_FILE", "tenants.json"),
+		AuthReplayWindow:      getEnvDuration("AUTH_REPLAY_WINDOW", 5*time.Minute),
+		AuthTestKey:           getEnv("AUTH_TEST_KEY", ""),
+		SessionSecret:         getEnv("SESSION_SECRET", ""),
+		SessionTTL:            getEnvDuration("SESSION_TTL", 8*time.Hour),
+		GameLaunchURL:         getEnv("GAME_LAUNCH_URL", ""),
+		WebSocketKeepAlive:    getEnvDuration("WS_KEEPALIVE", 25*time.Second),
+		AutoplayMaxRounds:     getEnvInt("AUTOPLAY_MAX_ROUNDS", 100),
+		ReelStripsFile:        getEnv("REEL_STRIPS_FILE", ""),
+		PaytableFile:          getEnv("PAYTABLE_FILE", ""),
+		JackpotsFile:          getEnv("JACKPOTS_FILE", ""),
+		TournamentsFile:       getEnv("TOURNAMENTS_FILE", ""),
+		MissionsFile:          getEnv("MISSIONS_FILE", ""),
+		MissionsProgressFile:  getEnv("MISSIONS_PROGRESS_FILE", ""),
+		MissionsSaveInterval:  getEnvDuration("MISSIONS_SAVE_INTERVAL", 10*time.Second),
+		RoundRecovery:         getEnv("ROUND_RECOVERY", "resume"),
+		RoundSettleTimeout:    getEnvDuration("ROUND_SETTLE_TIMEOUT", 30*time.Minute),
+		HTTPTimeout:           getEnvDuration("HTTP_TIMEOUT", 5*time.Second),
+		HTTPDialTimeout:       getEnvDuration("HTTP_DIAL_TIMEOUT", 2*time.Second),
+		RNGMaxRetries:         uint64(getEnvInt("RNG_MAX_RETRIES", 3)),
+		RNGBreakerThreshold:   getEnvInt("RNG_BREAKER_THRESHOLD", 5),
+		RNGBreakerCooldown:    getEnvDuration("RNG_BREAKER_COOLDOWN", 30*time.Second),
+		RNGFallbackPolicy:     getEnv("RNG_FALLBACK_POLICY", "error"),
+		RNGMode:               getEnv("RNG_MODE", "http"),
+		RNGFixedOutcome:       getEnv("RNG_FIXED_OUTCOME", "win"),
+		RNGScript:             getEnv("RNG_SCRIPT", "win,loss"),
+		RNGWinProbability:     getEnvFloat("RNG_WIN_PROBABILITY", 0.3),
+		RNGSeed:               int64(getEnvInt("RNG_SEED", 1)),
+		SettingsMode:          getEnv("SETTINGS_MODE", "http"),
+		StaticRTP:             getEnv("STATIC_RTP", "96"),
+		StaticBets:            getEnv("STATIC_BETS", ""), // Empty uses the currency's default ladder
+		StaticWins:            getEnv("STATIC_WINS", ""),
+		MaxWinMultiplier:      getEnvFloat("MAX_WIN_MULTIPLIER", 5000),
+		SettingsCacheTTL:      getEnvDuration("SETTINGS_CACHE_TTL", 30*time.Second),
+		SettingsCacheStaleTTL: getEnvDuration("SETTINGS_CACHE_STALE_TTL", 5*time.Minute),
 	}
 }
 
@@ -39,24 +138,29 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
-// LoadAll loads both production and test configurations from environment variables
-func LoadAll() (prod Config, test Config) {
-	// Try to load .env file, but don't fail if it doesn't exist
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found or error loading it")
+// getEnvInt reads an integer environment variable, falling back to the default if unset or invalid
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
 	}
+	return value
+}
 
-	prod = Config{
-		RNGServiceURL:      getEnv("PROD_RNG_API_URL", "http://159.89.235.166:17003/api/proxy/rng/1"),
-		SettingsServiceURL: getEnv("PROD_SETTINGS_API_URL", "https://t3.ibibe.africa/get-game-settings"),
-		ServerPort:         getEnv("PORT", "11400"),
-		LogFile:            getEnv("LOG_FILE", "app.log"),
+// getEnvFloat reads a decimal environment variable, falling back to the default if unset or invalid
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
 	}
-	test = Config{
-		RNGServiceURL:      getEnv("TEST_RNG_API_URL", "http://test-rng-url"),
-		SettingsServiceURL: getEnv("TEST_SETTINGS_API_URL", "https://test-settings-url"),
-		ServerPort:         getEnv("PORT", "11400"),
-		LogFile:            getEnv("LOG_FILE", "app.log"),
+	return value
+}
+
+// getEnvDuration reads a duration such as "5s" or "250ms", falling back to the default if unset or invalid
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
 	}
-	return
+	return value
 }
@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Config holds the transport settings shared by the outbound service clients
+type Config struct {
+	Timeout             time.Duration // Overall deadline for a single request, including reading the body
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConnsPerHost int
+}
+
+// DefaultConfig returns conservative timeouts suitable for the RNG and settings services
+func DefaultConfig() Config {
+	return Config{
+		Timeout:             5 * time.Second,
+		DialTimeout:         2 * time.Second,
+		TLSHandshakeTimeout: 2 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConnsPerHost: 32,
+	}
+}
+
+// New creates an HTTP client with its own transport configured from cfg.
+// A single client should be created at startup and shared so connections are pooled.
+func New(cfg Config) *http.Client {
+	defaults := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaults.DialTimeout
+	}
+	if cfg.TLSHandshakeTimeout <= 0 {
+		cfg.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          cfg.MaxIdleConnsPerHost * 4,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		ResponseHeaderTimeout: cfg.Timeout,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+}
@@ -0,0 +1,111 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a monetary value in integer minor units of its currency (cents, fils, yen...)
+type Amount int64
+
+// RoundingMode decides how fractional minor units are resolved
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to the nearest increment, halves away from zero
+	RoundHalfUp RoundingMode = iota
+	// RoundDown truncates towards zero so a payout is never rounded up
+	RoundDown
+)
+
+// Currency describes how amounts in one currency are represented and rounded
+type Currency struct {
+	Code     string
+	Exponent int // Number of minor-unit digits: 2 for USD, 0 for JPY, 3 for KWD
+	// Denomination is the value of one paytable credit in minor units
+	Denomination Amount
+	// BetLadder is the default bet ladder in minor units, used when the operator configures none
+	BetLadder []Amount
+	// RoundingIncrement is the smallest payable step in minor units (e.g. 5 for CHF cash rounding); 0 means 1
+	RoundingIncrement Amount
+	Rounding          RoundingMode
+}
+
+// scale returns 10^Exponent
+func (c Currency) scale() float64 {
+	return math.Pow10(c.Exponent)
+}
+
+// FromMajor converts a decimal amount in major units to minor units.
+// It fails if the value carries more precision than the currency's minor unit.
+func (c Currency) FromMajor(value float64) (Amount, error) {
+	minor := value * c.scale()
+	rounded := math.Round(minor)
+	if math.Abs(minor-rounded) > 1e-6 {
+		return 0, fmt.Errorf("%s amount %v has more than %d decimal places", c.Code, value, c.Exponent)
+	}
+	return Amount(rounded), nil
+}
+
+// RoundMajor converts a decimal amount in major units to minor units applying the currency's rounding rule
+func (c Currency) RoundMajor(value float64) Amount {
+	return c.roundMinor(value * c.scale())
+}
+
+// ToMajor converts minor units to a decimal amount in major units for JSON responses
+func (c Currency) ToMajor(a Amount) float64 {
+	return float64(a) / c.scale()
+}
+
+// Mul multiplies an amount by a factor (e.g. a free spin multiplier) applying the currency's rounding rule
+func (c Currency) Mul(a Amount, factor float64) Amount {
+	return c.roundMinor(float64(a) * factor)
+}
+
+// Round applies the currency's rounding increment to an amount already in minor units
+func (c Currency) Round(a Amount) Amount {
+	return c.roundMinor(float64(a))
+}
+
+func (c Currency) roundMinor(minor float64) Amount {
+	increment := float64(c.RoundingIncrement)
+	if increment <= 0 {
+		increment = 1
+	}
+	steps := minor / increment
+	switch c.Rounding {
+	case RoundDown:
+		// Guard against representation error such as 2.9999999 for an exact 3
+		steps = math.Trunc(steps + 1e-9)
+	default:
+		steps = math.Round(steps)
+	}
+	return Amount(steps * increment)
+}
+
+// Format renders an amount with the currency's number of decimals, e.g. "12.50 USD"
+func (c Currency) Format(a Amount) string {
+	return strconv.FormatFloat(c.ToMajor(a), 'f', c.Exponent, 64) + " " + c.Code
+}
+
+// currencies is the table of supported currencies keyed by ISO 4217 code
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Exponent: 2, Denomination: 1, BetLadder: []Amount{10, 20, 30, 50, 100}},
+	"EUR": {Code: "EUR", Exponent: 2, Denomination: 1, BetLadder: []Amount{10, 20, 30, 50, 100}},
+	"GBP": {Code: "GBP", Exponent: 2, Denomination: 1, BetLadder: []Amount{10, 20, 30, 50, 100}},
+	"CHF": {Code: "CHF", Exponent: 2, Denomination: 5, BetLadder: []Amount{50, 100, 150, 250, 500}, RoundingIncrement: 5},
+	"JPY": {Code: "JPY", Exponent: 0, Denomination: 1, BetLadder: []Amount{10, 20, 30, 50, 100}},
+	"KES": {Code: "KES", Exponent: 2, Denomination: 100, BetLadder: []Amount{1000, 2000, 3000, 5000, 10000}},
+	"NGN": {Code: "NGN", Exponent: 2, Denomination: 1000, BetLadder: []Amount{10000, 20000, 30000, 50000, 100000}},
+	"TZS": {Code: "TZS", Exponent: 2, Denomination: 2500, BetLadder: []Amount{25000, 50000, 75000, 125000, 250000}},
+	"UGX": {Code: "UGX", Exponent: 0, Denomination: 50, BetLadder: []Amount{500, 1000, 1500, 2500, 5000}, Rounding: RoundDown},
+	"KWD": {Code: "KWD", Exponent: 3, Denomination: 1, BetLadder: []Amount{30, 60, 90, 150, 300}},
+}
+
+// Lookup returns the currency for an ISO 4217 code
+func Lookup(code string) (Currency, bool) {
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
@@ -0,0 +1,176 @@
+package money
+
+import "testing"
+
+func mustLookup(t *testing.T, code string) Currency {
+	t.Helper()
+	c, ok := Lookup(code)
+	if !ok {
+		t.Fatalf("currency %s is not in the table", code)
+	}
+	return c
+}
+
+func TestFromMajor(t *testing.T) {
+	tests := []struct {
+		code    string
+		value   float64
+		want    Amount
+		wantErr bool
+	}{
+		{"USD", 12.5, 1250, false},
+		{"USD", 0.1, 10, false},
+		{"USD", 0.3, 30, false}, // 0.3 * 100 is 30.000000000000004
+		{"USD", -1.5, -150, false},
+		{"USD", 0.105, 0, true},
+		{"USD", 1.999, 0, true},
+		{"JPY", 100, 100, false},
+		{"JPY", 100.5, 0, true},
+		{"KWD", 1.234, 1234, false},
+		{"KWD", 0.001, 1, false},
+		{"KWD", 1.2345, 0, true},
+		{"CHF", 0.07, 7, false}, // Exact minor units are accepted whatever the rounding increment
+	}
+	for _, tt := range tests {
+		got, err := mustLookup(t, tt.code).FromMajor(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("FromMajor(%s %v) = %d, want an error", tt.code, tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("FromMajor(%s %v) = %d, %v, want %d", tt.code, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestRoundMajor(t *testing.T) {
+	tests := []struct {
+		code  string
+		value float64
+		want  Amount
+	}{
+		{"USD", 0.124, 12},
+		{"USD", 0.125, 13}, // Halves round away from zero
+		{"USD", -0.125, -13},
+		{"USD", 12.3456, 1235},
+		{"JPY", 99.4, 99},
+		{"JPY", 99.5, 100},
+		{"KWD", 0.0625, 63},
+		{"KWD", 1.23449, 1234},
+		{"CHF", 0.12, 10}, // 5 minor unit increments
+		{"CHF", 0.125, 15},
+		{"CHF", 0.13, 15},
+		{"UGX", 99.9, 99}, // Rounds down
+		{"UGX", 99.5, 99},
+		{"UGX", -99.9, -99},
+	}
+	for _, tt := range tests {
+		if got := mustLookup(t, tt.code).RoundMajor(tt.value); got != tt.want {
+			t.Errorf("RoundMajor(%s %v) = %d, want %d", tt.code, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		code   string
+		a      Amount
+		factor float64
+		want   Amount
+	}{
+		{"USD", 10, 1.5, 15},
+		{"USD", 15, 2.5, 38},
+		{"USD", 1, 0.5, 1},
+		{"USD", 100, 0, 0},
+		{"JPY", 7, 1.5, 11},
+		{"KWD", 333, 1.5, 500},
+		{"CHF", 50, 1.5, 75},
+		{"CHF", 35, 1.5, 55},   // 52.5 is 10.5 increments, rounded to 11
+		{"UGX", 10, 0.3, 3},    // 3.0000000000000004
+		{"UGX", 100, 0.29, 29}, // 28.999999999999996 is an exact 29, not 28
+		{"UGX", 7, 1.5, 10},
+	}
+	for _, tt := range tests {
+		if got := mustLookup(t, tt.code).Mul(tt.a, tt.factor); got != tt.want {
+			t.Errorf("Mul(%s %d, %v) = %d, want %d", tt.code, tt.a, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestRoundMinor(t *testing.T) {
+	tests := []struct {
+		name      string
+		increment Amount
+		rounding  RoundingMode
+		minor     float64
+		want      Amount
+	}{
+		{"no increment", 0, RoundHalfUp, 12.5, 13},
+		{"increment 1", 1, RoundHalfUp, 12.49, 12},
+		{"increment 5 below half", 5, RoundHalfUp, 12.4, 10},
+		{"increment 5 half", 5, RoundHalfUp, 12.5, 15},
+		{"increment 5 negative half", 5, RoundHalfUp, -12.5, -15},
+		{"increment 10", 10, RoundHalfUp, 149, 150},
+		{"down", 0, RoundDown, 12.9, 12},
+		{"down representation error", 0, RoundDown, 2.9999999999, 3},
+		{"down increment 5", 5, RoundDown, 14, 10},
+		{"down increment 5 exact", 5, RoundDown, 15, 15},
+		{"down negative", 5, RoundDown, -14, -10},
+	}
+	for _, tt := range tests {
+		c := Currency{Code: "TST", Exponent: 2, RoundingIncrement: tt.increment, Rounding: tt.rounding}
+		if got := c.roundMinor(tt.minor); got != tt.want {
+			t.Errorf("%s: roundMinor(%v) = %d, want %d", tt.name, tt.minor, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		code string
+		a    Amount
+		want Amount
+	}{
+		{"USD", 13, 13},
+		{"CHF", 12, 10},
+		{"CHF", 13, 15},
+		{"JPY", 13, 13},
+	}
+	for _, tt := range tests {
+		if got := mustLookup(t, tt.code).Round(tt.a); got != tt.want {
+			t.Errorf("Round(%s %d) = %d, want %d", tt.code, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		code string
+		a    Amount
+		want string
+	}{
+		{"USD", 1250, "12.50 USD"},
+		{"USD", 5, "0.05 USD"},
+		{"USD", -5, "-0.05 USD"},
+		{"JPY", 100, "100 JPY"},
+		{"KWD", 1234, "1.234 KWD"},
+		{"KWD", 30, "0.030 KWD"},
+		{"CHF", 5, "0.05 CHF"},
+	}
+	for _, tt := range tests {
+		if got := mustLookup(t, tt.code).Format(tt.a); got != tt.want {
+			t.Errorf("Format(%s %d) = %q, want %q", tt.code, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if c, ok := Lookup("usd"); !ok || c.Code != "USD" {
+		t.Errorf("Lookup(usd) = %q, %v, want USD", c.Code, ok)
+	}
+	if _, ok := Lookup("XXX"); ok {
+		t.Error("Lookup(XXX) found a currency")
+	}
+}
@@ -0,0 +1,81 @@
+package responsible
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+)
+
+// IsBlock reports whether err is a responsible-gaming block rather than a failure
+func IsBlock(err error) bool {
+	return apierror.Is(err, apierror.SelfExcluded, apierror.LossLimitReached, apierror.WagerLimitReached,
+		apierror.DepositLimitReached, apierror.SessionTimeLimit, apierror.RealityCheckRequired)
+}
+
+// Duration is a time.Duration read from JSON as a Go duration string such as "24h"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON renders the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// WindowLimit caps an amount over a rolling window. Amount is in major units of the session currency.
+type WindowLimit struct {
+	Window Duration `json:"window"`
+	Amount float64  `json:"amount"`
+}
+
+// Limits are amount limits over rolling windows; every limit in every list must hold
+type Limits struct {
+	Loss    []WindowLimit `json:"loss_limits"`    // Net loss (wagers minus wins)
+	Wager   []WindowLimit `json:"wager_limits"`   // Total wagered
+	Deposit []WindowLimit `json:"deposit_limits"` // Total deposited, as reported by the operator
+}
+
+// Policy is an operator's responsible-gaming configuration.
+// Limits apply to every player; players may add tighter limits of their own.
+type Policy struct {
+	Limits
+	MaxSessionDuration   Duration `json:"max_session_duration"`   // 0 means unlimited
+	RealityCheckInterval Duration `json:"reality_check_interval"` // 0 disables reality checks
+}
+
+// validate checks the policy for nonsensical values
+func (l Limits) validate() error {
+	for _, list := range [][]WindowLimit{l.Loss, l.Wager, l.Deposit} {
+		for _, limit := range list {
+			if limit.Window <= 0 {
+				return fmt.Errorf("limit window must be positive")
+			}
+			if limit.Amount < 0 {
+				return fmt.Errorf("limit amount must not be negative")
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the policy for nonsensical values
+func (p Policy) Validate() error {
+	if p.MaxSessionDuration < 0 || p.RealityCheckInterval < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	return p.Limits.validate()
+}
@@ -0,0 +1,289 @@
+package responsible
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+)
+
+// Player identifies a player within an operator
+type Player struct {
+	ClientID string
+	PlayerID string
+}
+
+func (p Player) key() string {
+	return p.ClientID + "|" + p.PlayerID
+}
+
+// Session identifies the player session a bet is placed in
+type Session struct {
+	ID      string
+	Started time.Time
+}
+
+// entry is one wager, win or deposit in minor units of currency
+type entry struct {
+	at       time.Time
+	currency string
+	wager    money.Amount
+	win      money.Amount
+	deposit  money.Amount
+}
+
+// playerState is everything tracked for one player
+type playerState struct {
+	entries       []entry
+	limits        Limits
+	excludedUntil time.Time
+}
+
+// sessionState tracks one session for reality checks
+type sessionState struct {
+	started      time.Time
+	lastCheck    time.Time
+	lastActivity time.Time
+	wagered      money.Amount
+	won          money.Amount
+}
+
+// retention bounds how long entries are kept; rolling windows longer than this see only this much history
+const retention = 31 * 24 * time.Hour
+
+// sessionIdleExpiry drops sessions that have not been played for this long
+const sessionIdleExpiry = 24 * time.Hour
+
+// Tracker keeps rolling wager, win and deposit history per player and enforces responsible-gaming policies.
+// State is held in memory, so it is per instance and does not survive a restart.
+type Tracker struct {
+	mu        sync.Mutex
+	players   map[string]*playerState
+	sessions  map[string]*sessionState
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		players:  make(map[string]*playerState),
+		sessions: make(map[string]*sessionState),
+		now:      time.Now,
+	}
+}
+
+// player returns the state for p, creating it if needed; the caller holds t.mu
+func (t *Tracker) player(p Player) *playerState {
+	state, ok := t.players[p.key()]
+	if !ok {
+		state = &playerState{}
+		t.players[p.key()] = state
+	}
+	return state
+}
+
+// session returns the state for s, creating it if needed; the caller holds t.mu
+func (t *Tracker) session(s Session, now time.Time) *sessionState {
+	state, ok := t.sessions[s.ID]
+	if !ok {
+		started := s.Started
+		if started.IsZero() {
+			started = now
+		}
+		state = &sessionState{started: started, lastCheck: started, lastActivity: now}
+		t.sessions[s.ID] = state
+	}
+	return state
+}
+
+// CheckBet evaluates the policy and the player's own limits before a bet of stake (minor units of cur).
+// It returns an *apierror.Error describing the first rule that blocks the bet.
+func (t *Tracker) CheckBet(policy Policy, p Player, s Session, stake money.Amount, cur money.Currency) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	player := t.player(p)
+	if err := t.checkExclusion(player, now); err != nil {
+		return err
+	}
+
+	session := t.session(s, now)
+	if policy.MaxSessionDuration > 0 {
+		if elapsed := now.Sub(session.started); elapsed >= time.Duration(policy.MaxSessionDuration) {
+			return apierror.New(apierror.SessionTimeLimit, "").WithDetails(map[string]interface{}{
+				"sessionMinutes": int(elapsed.Minutes()),
+				"limitMinutes":   int(time.Duration(policy.MaxSessionDuration).Minutes()),
+			})
+		}
+	}
+	if policy.RealityCheckInterval > 0 && now.Sub(session.lastCheck) >= time.Duration(policy.RealityCheckInterval) {
+		return apierror.New(apierror.RealityCheckRequired, "").WithDetails(t.sessionSummary(session, cur, now))
+	}
+
+	for _, limits := range []Limits{policy.Limits, player.limits} {
+		for _, limit := range limits.Wager {
+			wagered, _, _ := player.totals(cur.Code, now.Add(-time.Duration(limit.Window)))
+			if limitAmount := cur.RoundMajor(limit.Amount); wagered+stake > limitAmount {
+				return limitError(apierror.WagerLimitReached, "Wager limit reached", limit, cur, wagered, limitAmount)
+			}
+		}
+		for _, limit := range limits.Loss {
+			wagered, won, _ := player.totals(cur.Code, now.Add(-time.Duration(limit.Window)))
+			// A bet is refused if losing it would take the net loss past the limit
+			if limitAmount := cur.RoundMajor(limit.Amount); wagered-won+stake > limitAmount {
+				return limitError(apierror.LossLimitReached, "Loss limit reached", limit, cur, wagered-won, limitAmount)
+			}
+		}
+	}
+	return nil
+}
+
+// Record adds a settled step: the amount wagered (0 for free play) and the amount won, in minor units of cur
+func (t *Tracker) Record(p Player, s Session, wager, win money.Amount, cur money.Currency) {
+	if wager == 0 && win == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	player := t.player(p)
+	player.entries = append(player.entries, entry{at: now, currency: cur.Code, wager: wager, win: win})
+
+	session := t.session(s, now)
+	session.wagered += wager
+	session.won += win
+	session.lastActivity = now
+
+	t.sweep(now)
+}
+
+// RecordDeposit checks a deposit (minor units of cur) against the deposit limits and records it if allowed
+func (t *Tracker) RecordDeposit(policy Policy, p Player, amount money.Amount, cur money.Currency) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	player := t.player(p)
+	if err := t.checkExclusion(player, now); err != nil {
+		return err
+	}
+	for _, limits := range []Limits{policy.Limits, player.limits} {
+		for _, limit := range limits.Deposit {
+			_, _, deposited := player.totals(cur.Code, now.Add(-time.Duration(limit.Window)))
+			if limitAmount := cur.RoundMajor(limit.Amount); deposited+amount > limitAmount {
+				return limitError(apierror.DepositLimitReached, "Deposit limit reached", limit, cur, deposited, limitAmount)
+			}
+		}
+	}
+	player.entries = append(player.entries, entry{at: now, currency: cur.Code, deposit: amount})
+	return nil
+}
+
+// AcknowledgeRealityCheck restarts the reality-check interval of a session and returns its summary
+func (t *Tracker) AcknowledgeRealityCheck(s Session, cur money.Currency) map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	session := t.session(s, now)
+	session.lastCheck = now
+	return t.sessionSummary(session, cur, now)
+}
+
+// SetPlayerLimits replaces the limits a player chose for themselves; they apply on top of the operator's
+func (t *Tracker) SetPlayerLimits(p Player, limits Limits) error {
+	if err := limits.validate(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.player(p).limits = limits
+	return nil
+}
+
+// SelfExclude blocks the player until the given time; a zero time lifts the exclusion
+func (t *Tracker) SelfExclude(p Player, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.player(p).excludedUntil = until
+}
+
+// CheckExclusion returns an *apierror.Error if the player is currently self-excluded
+func (t *Tracker) CheckExclusion(p Player) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.checkExclusion(t.player(p), t.now())
+}
+
+func (t *Tracker) checkExclusion(player *playerState, now time.Time) error {
+	if now.Before(player.excludedUntil) {
+		return apierror.New(apierror.SelfExcluded, "").WithDetails(map[string]interface{}{
+			"until": player.excludedUntil.UTC().Format(time.RFC3339),
+		})
+	}
+	return nil
+}
+
+// totals sums wagers, wins and deposits in currency since the given time
+func (p *playerState) totals(currency string, since time.Time) (wagered, won, deposited money.Amount) {
+	for _, e := range p.entries {
+		if e.currency != currency || e.at.Before(since) {
+			continue
+		}
+		wagered += e.wager
+		won += e.win
+		deposited += e.deposit
+	}
+	return wagered, won, deposited
+}
+
+// sessionSummary is what the client shows at a reality check; the caller holds t.mu
+func (t *Tracker) sessionSummary(session *sessionState, cur money.Currency, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sessionMinutes": int(now.Sub(session.started).Minutes()),
+		"currency":       cur.Code,
+		"wagered":        cur.ToMajor(session.wagered),
+		"won":            cur.ToMajor(session.won),
+		"net":            cur.ToMajor(session.won - session.wagered),
+	}
+}
+
+// sweep drops entries past retention and idle sessions, at most every few minutes; the caller holds t.mu
+func (t *Tracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < 5*time.Minute {
+		return
+	}
+	t.lastSweep = now
+
+	cutoff := now.Add(-retention)
+	for _, player := range t.players {
+		kept := player.entries[:0]
+		for _, e := range player.entries {
+			if e.at.After(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		player.entries = kept
+	}
+	for id, session := range t.sessions {
+		if now.Sub(session.lastActivity) > sessionIdleExpiry {
+			delete(t.sessions, id)
+		}
+	}
+}
+
+// limitError builds the error for an amount limit
+func limitError(code apierror.Code, message string, limit WindowLimit, cur money.Currency, current, limitAmount money.Amount) *apierror.Error {
+	return apierror.New(code, fmt.Sprintf("%s: %s per %s", message, cur.Format(limitAmount), time.Duration(limit.Window))).
+		WithDetails(map[string]interface{}{
+			"currency": cur.Code,
+			"limit":    cur.ToMajor(limitAmount),
+			"current":  cur.ToMajor(current),
+			"window":   time.Duration(limit.Window).String(),
+		})
+}
@@ -0,0 +1,126 @@
+package rng
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when the breaker is rejecting calls to the RNG service
+var ErrCircuitOpen = errors.New("RNG circuit breaker is open")
+
+// BreakerState is the current state of a CircuitBreaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Calls flow normally
+	BreakerOpen                         // Calls are rejected until the cooldown elapses
+	BreakerHalfOpen                     // A single probe call is allowed through
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calling a failing service after a run of consecutive failures.
+// Once the cooldown has elapsed a single probe is let through; its result decides
+// whether the breaker closes again or re-opens for another cooldown.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	probeInFlight    bool
+	now              func() time.Time
+}
+
+// NewCircuitBreaker creates a breaker that opens after failureThreshold consecutive failures
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		state:            BreakerClosed,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed. Every successful Allow must be
+// followed by exactly one call to Success or Failure.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probeInFlight = true
+		log.Printf("RNG circuit breaker half-open, sending probe")
+		return nil
+	case BreakerHalfOpen:
+		if b.probeInFlight {
+			return ErrCircuitOpen
+		}
+		b.probeInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a successful call and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerClosed {
+		log.Printf("RNG circuit breaker closed after successful probe")
+	}
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probeInFlight = false
+}
+
+// Failure records a failed call, opening the breaker when the threshold is reached
+// or immediately if the failed call was the half-open probe
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probeInFlight = false
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		if b.state != BreakerOpen {
+			log.Printf("RNG circuit breaker opened after %d consecutive failures", b.failures)
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
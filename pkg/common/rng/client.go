@@ -4,21 +4,67 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/google/uuid"
 )
 
+// ErrUnavailable is returned when the RNG service cannot produce an outcome
+// and the client is configured to surface the failure instead of falling back
+var ErrUnavailable = errors.New("RNG service unavailable")
+
+// FallbackPolicy decides what GetOutcome returns when the RNG service is unavailable
+type FallbackPolicy string
+
+const (
+	// FallbackError fails the call so the handler rejects the request
+	FallbackError FallbackPolicy = "error"
+	// FallbackLoss resolves the call as a loss so no unverified win is ever paid
+	FallbackLoss FallbackPolicy = "loss"
+)
+
+// Options configures the resilience behaviour of the RNG client
+type Options struct {
+	HTTPClient       *http.Client
+	MaxRetries       uint64
+	MaxElapsedTime   time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	Fallback         FallbackPolicy
+}
+
 // Client for RNG service
 type Client struct {
-	ServiceURL string
+	ServiceURL     string
+	HTTPClient     *http.Client
+	MaxRetries     uint64
+	MaxElapsedTime time.Duration
+	Breaker        *CircuitBreaker
+	Fallback       FallbackPolicy
 }
 
 // NewClient creates a new RNG client
-func NewClient(serviceURL string) *Client {
+func NewClient(serviceURL string, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxElapsedTime <= 0 {
+		opts.MaxElapsedTime = 10 * time.Second
+	}
+	if opts.Fallback == "" {
+		opts.Fallback = FallbackError
+	}
 	return &Client{
-		ServiceURL: serviceURL,
+		ServiceURL:     serviceURL,
+		HTTPClient:     opts.HTTPClient,
+		MaxRetries:     opts.MaxRetries,
+		MaxElapsedTime: opts.MaxElapsedTime,
+		Breaker:        NewCircuitBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		Fallback:       opts.Fallback,
 	}
 }
 
@@ -31,18 +77,23 @@ type Request struct {
 	PayoutMultiplier float64 `json:"payout_multiplier"`
 	RequestSalt      string  `json:"request_salt"`
 	BetAmount        float64 `json:"bet_amount"`
+	Currency         string  `json:"currency"`
 	IPAddress        string  `json:"ip_address"`
-    UserAgent        string  `json:"user_agent"`
+	UserAgent        string  `json:"user_agent"`
 }
 
 type Response struct {
 	PrefOutcome string  `json:"pref_outcome"`
 	WinAmount   float64 `json:"win_amount"`
 	WinProb     float64 `json:"win_prob"`
+	// Fallback is set when the outcome was produced by the fallback policy rather than the RNG service
+	Fallback bool `json:"-"`
 }
 
-// GetOutcome calls the RNG service and returns the outcome
-func (c *Client) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, ipAddress string, userAgent string) (Response, error) {
+// GetOutcome calls the RNG service and returns the outcome.
+// The request is built once so every retry carries the same RequestSalt,
+// which lets the RNG service de-duplicate attempts that did reach it.
+func (c *Client) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (Response, error) {
 	reqBody, err := json.Marshal(Request{
 		ClientID:         clientID,
 		GameID:           gameID,
@@ -52,6 +103,7 @@ func (c *Client) GetOutcome(clientID, gameID, playerID, betID string, rtp, payou
 		PayoutMultiplier: payoutMultiplier,
 		RequestSalt:      uuid.New().String(),
 		BetAmount:        betAmount,
+		Currency:         currency,
 		IPAddress:        ipAddress,
 		UserAgent:        userAgent,
 	})
@@ -62,23 +114,64 @@ func (c *Client) GetOutcome(clientID, gameID, playerID, betID string, rtp, payou
 
 	log.Printf("RNG request: %s", string(reqBody))
 
-	resp, err := http.Post(c.ServiceURL, "application/json", bytes.NewBuffer(reqBody))
-	if err != nil {
-		log.Printf("Error calling RNG API: %v", err)
-		return Response{}, err
+	if err := c.Breaker.Allow(); err != nil {
+		log.Printf("RNG call skipped: %v", err)
+		return c.fallback(err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("RNG API returned non-200 status: %d", resp.StatusCode)
-		return Response{}, errors.New("RNG API call failed")
+	var rngResp Response
+	operation := func() error {
+		resp, err := c.HTTPClient.Post(c.ServiceURL, "application/json", bytes.NewReader(reqBody))
+		if err != nil {
+			log.Printf("Error calling RNG API: %v", err)
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			log.Printf("RNG API returned retryable status: %d", resp.StatusCode)
+			return fmt.Errorf("RNG API call failed with status %d", resp.StatusCode)
+		}
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("RNG API returned non-200 status: %d", resp.StatusCode)
+			return backoff.Permanent(fmt.Errorf("RNG API call rejected with status %d", resp.StatusCode))
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&rngResp); err != nil {
+			log.Printf("Error decoding RNG response: %v", err)
+			return err
+		}
+
+		return nil
 	}
 
-	var rngResp Response
-	if err := json.NewDecoder(resp.Body).Decode(&rngResp); err != nil {
-		log.Printf("Error decoding RNG response: %v", err)
+	// Retry with exponential backoff, bounded by both attempts and total time
+	policy := backoff.WithMaxRetries(backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(100*time.Millisecond),
+		backoff.WithMaxElapsedTime(c.MaxElapsedTime),
+	), c.MaxRetries)
+	err = backoff.Retry(operation, policy)
+
+	var permanent *backoff.PermanentError
+	switch {
+	case err == nil:
+		c.Breaker.Success()
+		return rngResp, nil
+	case errors.As(err, &permanent):
+		// The service answered, so it is healthy; the request itself was refused
+		c.Breaker.Success()
 		return Response{}, err
+	default:
+		c.Breaker.Failure()
+		return c.fallback(err)
 	}
+}
 
-	return rngResp, nil
+// fallback applies the configured policy after the RNG service could not be reached
+func (c *Client) fallback(cause error) (Response, error) {
+	if c.Fallback == FallbackLoss {
+		log.Printf("RNG unavailable (%v), applying loss fallback", cause)
+		return Response{PrefOutcome: "loss", Fallback: true}, nil
+	}
+	return Response{}, fmt.Errorf("%w: %v", ErrUnavailable, cause)
 }
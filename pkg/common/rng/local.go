@@ -0,0 +1,102 @@
+package rng
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+)
+
+// Outcome values understood by the game handlers
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+)
+
+// FixedOutcome always returns the same outcome without contacting the RNG service.
+// Useful for offline demos and for forcing a path through the handlers.
+type FixedOutcome struct {
+	Outcome string
+}
+
+// NewFixedOutcome creates a provider that always returns outcome
+func NewFixedOutcome(outcome string) *FixedOutcome {
+	return &FixedOutcome{Outcome: outcome}
+}
+
+// GetOutcome returns the fixed outcome
+func (f *FixedOutcome) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (Response, error) {
+	return localResponse(f.Outcome, payoutMultiplier, betAmount, 1), nil
+}
+
+// ScriptedOutcome replays a fixed sequence of outcomes, one per call.
+// When Loop is set the sequence restarts after the last entry; otherwise it is an error to run past the end.
+type ScriptedOutcome struct {
+	mu       sync.Mutex
+	outcomes []string
+	next     int
+	Loop     bool
+}
+
+// NewScriptedOutcome creates a provider that returns outcomes in order
+func NewScriptedOutcome(outcomes []string, loop bool) *ScriptedOutcome {
+	return &ScriptedOutcome{outcomes: outcomes, Loop: loop}
+}
+
+// GetOutcome returns the next scripted outcome
+func (s *ScriptedOutcome) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= len(s.outcomes) {
+		if !s.Loop || len(s.outcomes) == 0 {
+			return Response{}, fmt.Errorf("scripted RNG exhausted after %d outcomes", len(s.outcomes))
+		}
+		s.next = 0
+	}
+	outcome := s.outcomes[s.next]
+	s.next++
+	return localResponse(outcome, payoutMultiplier, betAmount, 1), nil
+}
+
+// ProbabilityOutcome draws a win with a fixed probability from a seeded generator,
+// so a given seed always yields the same sequence of outcomes
+type ProbabilityOutcome struct {
+	mu             sync.Mutex
+	winProbability float64
+	r              *rand.Rand
+}
+
+// NewProbabilityOutcome creates a provider that wins with probability winProbability
+func NewProbabilityOutcome(winProbability float64, seed int64) *ProbabilityOutcome {
+	if winProbability < 0 || winProbability > 1 {
+		log.Printf("Win probability %.3f out of range, clamping", winProbability)
+		winProbability = min(max(winProbability, 0), 1)
+	}
+	return &ProbabilityOutcome{
+		winProbability: winProbability,
+		r:              rand.New(rand.NewSource(seed)),
+	}
+}
+
+// GetOutcome draws the next outcome
+func (p *ProbabilityOutcome) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (Response, error) {
+	p.mu.Lock()
+	roll := p.r.Float64()
+	p.mu.Unlock()
+
+	outcome := OutcomeLoss
+	if roll < p.winProbability {
+		outcome = OutcomeWin
+	}
+	return localResponse(outcome, payoutMultiplier, betAmount, p.winProbability), nil
+}
+
+// localResponse builds a response shaped like the RNG service's
+func localResponse(outcome string, payoutMultiplier, betAmount, winProb float64) Response {
+	resp := Response{PrefOutcome: outcome, WinProb: winProb}
+	if outcome != OutcomeLoss {
+		resp.WinAmount = payoutMultiplier * betAmount
+	}
+	return resp
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+// localsClaims is the fiber.Ctx locals key holding the verified session claims
+const localsClaims = "session.claims"
+
+// Middleware requires a valid "Authorization: Bearer <token>" header and stores the claims on the context
+func Middleware(issuer *Issuer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || token == "" {
+			return apierror.Write(c, apierror.New(apierror.SessionRequired, ""))
+		}
+
+		claims, err := issuer.Verify(token)
+		if err != nil {
+			code := apierror.SessionInvalid
+			if errors.Is(err, ErrExpiredToken) {
+				code = apierror.SessionExpired
+			}
+			return apierror.Write(c, apierror.Wrap(code, err))
+		}
+
+		c.Locals(localsClaims, claims)
+		return c.Next()
+	}
+}
+
+// FromContext returns the session claims verified by Middleware
+func FromContext(c *fiber.Ctx) (Claims, bool) {
+	claims, ok := c.Locals(localsClaims).(Claims)
+	return claims, ok
+}
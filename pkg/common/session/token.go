@@ -0,0 +1,108 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or carry a bad signature
+	ErrInvalidToken = errors.New("invalid session token")
+	// ErrExpiredToken is returned for correctly signed tokens past their expiry
+	ErrExpiredToken = errors.New("session expired")
+)
+
+// Claims is the player session bound into a token at launch
+type Claims struct {
+	SessionID   string  `json:"sid"`
+	ClientID    string  `json:"cid"`
+	PlayerID    string  `json:"pid"`
+	GameID      string  `json:"gid"`
+	Currency    string  `json:"cur"`
+	MinBet      float64 `json:"minBet,omitempty"`
+	MaxBet      float64 `json:"maxBet,omitempty"`
+	Environment string  `json:"env"`
+	IssuedAt    int64   `json:"iat"`
+	ExpiresAt   int64   `json:"exp"`
+}
+
+// Expiry returns the expiry time of the session
+func (c Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// Issuer mints and verifies signed session tokens.
+// A token is base64url(JSON claims) + "." + base64url(HMAC-SHA256(claims)).
+type Issuer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewIssuer creates an issuer signing with secret. If secret is empty a random key is generated,
+// which means tokens do not survive a restart and are not valid across instances.
+func NewIssuer(secret string, ttl time.Duration) *Issuer {
+	key := []byte(secret)
+	if len(key) == 0 {
+		log.Printf("WARNING: no session secret configured, using a random key")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("Error generating session key: %v", err)
+		}
+	}
+	if ttl <= 0 {
+		ttl = 8 * time.Hour
+	}
+	return &Issuer{key: key, ttl: ttl, now: time.Now}
+}
+
+// Issue fills in the session ID and validity period and returns the signed token
+func (i *Issuer) Issue(claims Claims) (string, Claims, error) {
+	now := i.now()
+	claims.SessionID = uuid.New().String()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(i.ttl).Unix()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + i.sign(encoded), claims, nil
+}
+
+// Verify checks the token signature and expiry and returns its claims
+func (i *Issuer) Verify(token string) (Claims, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(i.sign(encoded))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if !i.now().Before(claims.Expiry()) {
+		return Claims{}, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (i *Issuer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
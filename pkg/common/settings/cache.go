@@ -0,0 +1,162 @@
+package settings
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheOptions configures CachedClient
+type CacheOptions struct {
+	// TTL is how long a looked-up value is served without contacting the settings service
+	TTL time.Duration
+	// StaleTTL is how long past TTL a value may still be served while a background refresh runs.
+	// If the refresh fails the stale value keeps being served until this window closes.
+	StaleTTL time.Duration
+}
+
+// CacheStats is a snapshot of the cache counters
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`
+	StaleHits     uint64  `json:"staleHits"`
+	Misses        uint64  `json:"misses"`
+	Refreshes     uint64  `json:"refreshes"`
+	RefreshErrors uint64  `json:"refreshErrors"`
+	Entries       int     `json:"entries"`
+	HitRate       float64 `json:"hitRate"`
+}
+
+type cacheEntry struct {
+	settings  Settings
+	fetchedAt time.Time
+}
+
+// CachedClient wraps a Client with a per-player settings cache.
+// Concurrent lookups of the same key share a single call to the settings service.
+type CachedClient struct {
+	client   *Client
+	ttl      time.Duration
+	staleTTL time.Duration
+
+	mu        sync.RWMutex
+	entries   map[string]cacheEntry
+	lastSweep time.Time
+	group     singleflight.Group
+	now       func() time.Time
+
+	hits          atomic.Uint64
+	staleHits     atomic.Uint64
+	misses        atomic.Uint64
+	refreshes     atomic.Uint64
+	refreshErrors atomic.Uint64
+}
+
+// NewCachedClient creates a caching wrapper around a settings client
+func NewCachedClient(client *Client, opts CacheOptions) *CachedClient {
+	if opts.TTL <= 0 {
+		opts.TTL = 30 * time.Second
+	}
+	if opts.StaleTTL < 0 {
+		opts.StaleTTL = 0
+	}
+	return &CachedClient{
+		client:    client,
+		ttl:       opts.TTL,
+		staleTTL:  opts.StaleTTL,
+		entries:   make(map[string]cacheEntry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func cacheKey(clientID, gameID, playerID string) string {
+	return clientID + "\x00" + gameID + "\x00" + playerID
+}
+
+// GetSettings returns the cached settings for the player, refreshing them as needed
+func (c *CachedClient) GetSettings(clientID, gameID, playerID string) (Settings, error) {
+	key := cacheKey(clientID, gameID, playerID)
+
+	c.mu.RLock()
+	entry, found := c.entries[key]
+	c.mu.RUnlock()
+
+	if found {
+		age := c.now().Sub(entry.fetchedAt)
+		if age < c.ttl {
+			c.hits.Add(1)
+			return entry.settings, nil
+		}
+		if age < c.ttl+c.staleTTL {
+			// Serve the stale value now and refresh in the background
+			c.staleHits.Add(1)
+			go c.refresh(key, clientID, gameID, playerID)
+			return entry.settings, nil
+		}
+	}
+
+	c.misses.Add(1)
+	return c.refresh(key, clientID, gameID, playerID)
+}
+
+// refresh fetches the settings from the service, de-duplicating concurrent calls per key
+func (c *CachedClient) refresh(key, clientID, gameID, playerID string) (Settings, error) {
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		c.refreshes.Add(1)
+		s, err := c.client.GetSettings(clientID, gameID, playerID)
+		if err != nil {
+			c.refreshErrors.Add(1)
+			log.Printf("Settings cache refresh failed for client=%s game=%s player=%s: %v", clientID, gameID, playerID, err)
+			return Settings{}, err
+		}
+		c.store(key, s)
+		return s, nil
+	})
+	if err != nil {
+		return Settings{}, err
+	}
+	return value.(Settings), nil
+}
+
+// store saves a fresh value and periodically drops entries that can no longer be served
+func (c *CachedClient) store(key string, s Settings) {
+	now := c.now()
+	maxAge := c.ttl + c.staleTTL
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{settings: s, fetchedAt: now}
+	if now.Sub(c.lastSweep) < maxAge {
+		return
+	}
+	for k, e := range c.entries {
+		if now.Sub(e.fetchedAt) >= maxAge {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *CachedClient) Stats() CacheStats {
+	c.mu.RLock()
+	entries := len(c.entries)
+	c.mu.RUnlock()
+
+	stats := CacheStats{
+		Hits:          c.hits.Load(),
+		StaleHits:     c.staleHits.Load(),
+		Misses:        c.misses.Load(),
+		Refreshes:     c.refreshes.Load(),
+		RefreshErrors: c.refreshErrors.Load(),
+		Entries:       entries,
+	}
+	if total := stats.Hits + stats.StaleHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.StaleHits) / float64(total)
+	}
+	return stats
+}
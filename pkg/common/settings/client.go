@@ -1,88 +1,160 @@
 package settings
 
 import (
-    "bytes"
-    "encoding/json"
-    "errors"
-    "log"
-    "net/http"
-    "strconv"
-
-    "github.com/cenkalti/backoff/v4"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cenkalti/backoff/v4"
 )
 
 // Client for game settings service
 type Client struct {
-    ServiceURL string
+	ServiceURL string
+	HTTPClient *http.Client
 }
 
-// NewClient creates a new settings client
-func NewClient(serviceURL string) *Client {
-    return &Client{
-        ServiceURL: serviceURL,
-    }
+// NewClient creates a new settings client using the shared HTTP client
+func NewClient(serviceURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		ServiceURL: serviceURL,
+		HTTPClient: httpClient,
+	}
 }
 
 type Request struct {
-    ClientID string `json:"client_id"`
-    GameID   string `json:"game_id"`
-    PlayerID string `json:"player_id"`
+	ClientID string `json:"client_id"`
+	GameID   string `json:"game_id"`
+	PlayerID string `json:"player_id"`
 }
 
 type Response struct {
-    Data struct {
-        GameBets string `json:"game_bets"`
-        GameRTP  string `json:"game_rtp"`
-        GameWins string `json:"game_wins"`
-    } `json:"data"`
+	Data struct {
+		GameBets string `json:"game_bets"`
+		GameRTP  string `json:"game_rtp"`
+		GameWins string `json:"game_wins"`
+	} `json:"data"`
+}
+
+// Settings is the parsed game configuration the operator has set for a player
+type Settings struct {
+	RTP        float64
+	BetAmounts []float64 // Allowed bet ladder in ascending order; empty if the operator has none configured
+	MaxWin     float64   // Maximum payout for a single round; 0 means uncapped
+	// MaxWinMultiplier caps a round's payout as a multiple of the bet; 0 means the server default applies
+	MaxWinMultiplier float64
+}
+
+// GetSettings retrieves and parses the full game settings for a player with retry logic (Improvement #4)
+func (c *Client) GetSettings(clientID, gameID, playerID string) (Settings, error) {
+	reqBody, err := json.Marshal(Request{
+		ClientID: clientID,
+		GameID:   gameID,
+		PlayerID: playerID,
+	})
+	if err != nil {
+		log.Printf("Error marshaling settings request: %v", err)
+		return Settings{}, err
+	}
+
+	log.Printf("Settings request: %s", string(reqBody))
+
+	var settingsResp Response
+	operation := func() error {
+		resp, err := c.HTTPClient.Post(c.ServiceURL, "application/json", bytes.NewReader(reqBody))
+		if err != nil {
+			log.Printf("Error calling settings API: %v", err)
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			log.Printf("Settings API returned non-200 status: %d", resp.StatusCode)
+			return errors.New("Settings API call failed")
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&settingsResp); err != nil {
+			log.Printf("Error decoding settings response: %v", err)
+			return err
+		}
+
+		return nil
+	}
+
+	// Retry with exponential backoff
+	err = backoff.Retry(operation, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3))
+	if err != nil {
+		return Settings{}, err
+	}
+
+	return parseSettings(settingsResp)
+}
+
+// parseSettings converts the raw string fields of the settings response
+func parseSettings(resp Response) (Settings, error) {
+	rtp, err := strconv.ParseFloat(strings.TrimSpace(resp.Data.GameRTP), 64)
+	if err != nil {
+		log.Printf("Error parsing RTP value: %v", err)
+		return Settings{}, err
+	}
+
+	bets, err := parseAmountList(resp.Data.GameBets)
+	if err != nil {
+		log.Printf("Error parsing game bets %q: %v", resp.Data.GameBets, err)
+		return Settings{}, err
+	}
+
+	settings := Settings{
+		RTP:        rtp,
+		BetAmounts: bets,
+	}
+
+	// game_wins is either an absolute amount ("250") or a bet multiple ("5000x")
+	if wins := strings.TrimSpace(resp.Data.GameWins); wins != "" {
+		isMultiple := strings.HasSuffix(strings.ToLower(wins), "x")
+		value, err := strconv.ParseFloat(strings.TrimRight(wins, "xX"), 64)
+		if err != nil || value < 0 {
+			log.Printf("Error parsing game wins %q: %v", resp.Data.GameWins, err)
+			return Settings{}, fmt.Errorf("invalid game_wins value %q", resp.Data.GameWins)
+		}
+		if isMultiple {
+			settings.MaxWinMultiplier = value
+		} else {
+			settings.MaxWin = value
+		}
+	}
+
+	return settings, nil
 }
 
-// GetRTP retrieves the RTP settings for a player with retry logic (Improvement #4)
-func (c *Client) GetRTP(clientID, gameID, playerID string) (float64, error) {
-    reqBody, err := json.Marshal(Request{
-        ClientID: clientID,
-        GameID:   gameID,
-        PlayerID: playerID,
-    })
-    if err != nil {
-        log.Printf("Error marshaling settings request: %v", err)
-        return 0, err
-    }
-
-    log.Printf("Settings request: %s", string(reqBody))
-
-    var settingsResp Response
-    operation := func() error {
-        resp, err := http.Post(c.ServiceURL, "application/json", bytes.NewBuffer(reqBody))
-        if err != nil {
-            log.Printf("Error calling settings API: %v", err)
-            return err
-        }
-        defer resp.Body.Close()
-
-        if resp.StatusCode != http.StatusOK {
-            log.Printf("Settings API returned non-200 status: %d", resp.StatusCode)
-            return errors.New("Settings API call failed")
-        }
-
-        if err := json.NewDecoder(resp.Body).Decode(&settingsResp); err != nil {
-            log.Printf("Error decoding settings response: %v", err)
-            return err
-        }
-
-        return nil
-    }
-
-    // Retry with exponential backoff
-    err = backoff.Retry(operation, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3))
-    if err != nil {
-        return 0, err
-    }
-
-    rtp, err := strconv.ParseFloat(settingsResp.Data.GameRTP, 64)
-    if err != nil {
-        log.Printf("Error parsing RTP value: %v", err)
-        return 0, err
-    }
-    return rtp, nil
-}
\ No newline at end of file
+// parseAmountList parses a bet ladder given either as "0.1,0.2,0.5" or as a JSON array
+func parseAmountList(raw string) ([]float64, error) {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, "["), "]")
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var amounts []float64
+	for _, part := range strings.Split(raw, ",") {
+		amount, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(part), `"`), 64)
+		if err != nil {
+			return nil, err
+		}
+		if amount <= 0 {
+			return nil, fmt.Errorf("bet amount must be positive, got %v", amount)
+		}
+		amounts = append(amounts, amount)
+	}
+	sort.Float64s(amounts)
+	return amounts, nil
+}
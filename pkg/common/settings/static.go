@@ -0,0 +1,26 @@
+package settings
+
+// StaticProvider serves the same settings for every player without contacting the settings service.
+// It is meant for offline demo deployments and for exercising the handlers deterministically.
+type StaticProvider struct {
+	Settings Settings
+}
+
+// NewStaticProvider parses the settings from the same string formats the settings service returns
+func NewStaticProvider(gameRTP, gameBets, gameWins string) (*StaticProvider, error) {
+	var resp Response
+	resp.Data.GameRTP = gameRTP
+	resp.Data.GameBets = gameBets
+	resp.Data.GameWins = gameWins
+
+	s, err := parseSettings(resp)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticProvider{Settings: s}, nil
+}
+
+// GetSettings returns the configured settings
+func (p *StaticProvider) GetSettings(clientID, gameID, playerID string) (Settings, error) {
+	return p.Settings, nil
+}
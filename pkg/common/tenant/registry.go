@@ -0,0 +1,160 @@
+package tenant
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
+)
+
+var (
+	// ErrUnknownTenant is returned for a client_id that is not in the registry
+	ErrUnknownTenant = errors.New("unknown client")
+	// ErrInvalidAPIKey is returned when the API key does not match the tenant's key
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// Environment is a set of backing services that tenants are routed to
+type Environment struct {
+	Name               string `json:"-"`
+	RNGServiceURL      string `json:"rng_url"`
+	SettingsServiceURL string `json:"settings_url"`
+}
+
+// Limits are per-tenant overrides of the server defaults; zero values mean "use the default"
+type Limits struct {
+	MaxWinMultiplier float64 `json:"max_win_multiplier"`
+	MaxBet           float64 `json:"max_bet"`
+}
+
+// Tenant is an operator integration identified by its client_id
+type Tenant struct {
+	ClientID    string `json:"client_id"`
+	APIKey      string `json:"api_key"`
+	Secret      string `json:"secret"` // Shared secret for HMAC request signing
+	Environment string `json:"environment"`
+	MathVariant string `json:"math_variant"`
+	Limits      Limits `json:"limits"`
+
+	// ResponsibleGaming is the operator's loss, wager and deposit limits, session limit and reality checks
+	ResponsibleGaming responsible.Policy `json:"responsible_gaming"`
+}
+
+// Registry maps client IDs to tenants and their environments
+type Registry struct {
+	environments map[string]Environment
+	tenants      map[string]Tenant
+}
+
+type registryFile struct {
+	Environments map[string]Environment `json:"environments"`
+	Tenants      []Tenant               `json:"tenants"`
+}
+
+// Load reads and validates a registry from a JSON file
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tenant registry: %w", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing tenant registry %s: %w", path, err)
+	}
+
+	registry, err := NewRegistry(file.Environments, file.Tenants)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Loaded tenant registry from %s: %d environments, %d tenants", path, len(registry.environments), len(registry.tenants))
+	return registry, nil
+}
+
+// NewRegistry validates the environments and tenants and builds a registry
+func NewRegistry(environments map[string]Environment, tenants []Tenant) (*Registry, error) {
+	if len(environments) == 0 {
+		return nil, errors.New("tenant registry defines no environments")
+	}
+
+	r := &Registry{
+		environments: make(map[string]Environment, len(environments)),
+		tenants:      make(map[string]Tenant, len(tenants)),
+	}
+	for name, env := range environments {
+		if env.RNGServiceURL == "" || env.SettingsServiceURL == "" {
+			return nil, fmt.Errorf("environment %q must define rng_url and settings_url", name)
+		}
+		env.Name = name
+		r.environments[name] = env
+	}
+
+	for _, t := range tenants {
+		if t.ClientID == "" {
+			return nil, errors.New("tenant without client_id")
+		}
+		if _, dup := r.tenants[t.ClientID]; dup {
+			return nil, fmt.Errorf("duplicate tenant %q", t.ClientID)
+		}
+		if _, ok := r.environments[t.Environment]; !ok {
+			return nil, fmt.Errorf("tenant %q references unknown environment %q", t.ClientID, t.Environment)
+		}
+		if t.MathVariant == "" {
+			t.MathVariant = "default"
+		}
+		if err := t.ResponsibleGaming.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %q responsible_gaming: %w", t.ClientID, err)
+		}
+		r.tenants[t.ClientID] = t
+	}
+	return r, nil
+}
+
+// Lookup returns the tenant for a client ID
+func (r *Registry) Lookup(clientID string) (Tenant, bool) {
+	t, ok := r.tenants[clientID]
+	return t, ok
+}
+
+// Authenticate resolves a client ID and checks its API key.
+// Tenants without a configured key accept any key.
+func (r *Registry) Authenticate(clientID, apiKey string) (Tenant, error) {
+	t, ok := r.tenants[clientID]
+	if !ok {
+		return Tenant{}, ErrUnknownTenant
+	}
+	if t.APIKey != "" && subtle.ConstantTimeCompare([]byte(t.APIKey), []byte(apiKey)) != 1 {
+		return Tenant{}, ErrInvalidAPIKey
+	}
+	return t, nil
+}
+
+// Secret returns the HMAC signing secret for a client, implementing auth.SecretStore
+func (r *Registry) Secret(clientID string) (string, bool) {
+	t, ok := r.tenants[clientID]
+	if !ok || t.Secret == "" {
+		return "", false
+	}
+	return t.Secret, true
+}
+
+// Environment returns a named environment
+func (r *Registry) Environment(name string) (Environment, bool) {
+	env, ok := r.environments[name]
+	return env, ok
+}
+
+// Environments returns all environments sorted by name
+func (r *Registry) Environments() []Environment {
+	envs := make([]Environment, 0, len(r.environments))
+	for _, env := range r.environments {
+		envs = append(envs, env)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	return envs
+}
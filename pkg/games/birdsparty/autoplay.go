@@ -0,0 +1,270 @@
+package birdsparty
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxRoundSteps bounds the stage-cleared steps and cascades the server plays for one round
+const maxRoundSteps = 200
+
+// AutoplayHandler handles the /autoplay/birdsparty endpoint
+// Plays up to the requested number of rounds server-side, resolving every cascade and
+// stage-cleared step, and returns a summary per round
+func (rg *RouteGroup) AutoplayHandler(c *fiber.Ctx) error {
+	var req AutoplayRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	// Resolve the tenant and its environment from the session token
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	resp, err := rg.playAutoplay(env, req, clientInfoFrom(c), nil)
+	if err != nil {
+		return apierror.Write(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// playAutoplay runs an autoplay request for a resolved session. onRound, if set, is called
+// after every round so transports can stream progress; an error from it stops the run.
+// The balance that stops the run is the client's own report, so it is advisory only; the operator's
+// wallet and the responsible-gaming limits decide what the player can stake.
+func (rg *RouteGroup) playAutoplay(env requestEnv, req AutoplayRequest, client clientInfo, onRound func(AutoplayRoundSummary) error) (AutoplayResponse, error) {
+	cur := env.Currency
+
+	if req.BetID == "" {
+		return AutoplayResponse{}, apierror.New(apierror.InvalidRequest, "bet_id is required")
+	}
+	if req.Rounds <= 0 {
+		return AutoplayResponse{}, apierror.New(apierror.InvalidRequest, "rounds must be at least 1")
+	}
+	if rg.AutoplayMaxRounds > 0 && req.Rounds > rg.AutoplayMaxRounds {
+		return AutoplayResponse{}, apierror.New(apierror.InvalidRequest, fmt.Sprintf("rounds must not exceed %d", rg.AutoplayMaxRounds))
+	}
+	balance, err := cur.FromMajor(req.Balance)
+	if err != nil || balance <= 0 {
+		return AutoplayResponse{}, apierror.New(apierror.InvalidRequest, "balance must be a positive amount in the session currency")
+	}
+
+	stop := req.StopConditions
+	winAbove := cur.RoundMajor(stop.WinAbove)
+	singleWinLimit := cur.RoundMajor(stop.SingleWinLimit)
+	lossLimit := cur.RoundMajor(stop.LossLimit)
+
+	resp := AutoplayResponse{
+		Status:        "success",
+		Message:       "",
+		GameState:     req.GameState,
+		Rounds:        []AutoplayRoundSummary{},
+		BalanceSource: BalanceSourceClient,
+	}
+	var totalCost, totalWin money.Amount
+
+	for round := 1; round <= req.Rounds; round++ {
+		// A paid spin needs the full bet in the balance; free spins cost nothing
+		if resp.GameState.GameMode != "freeSpins" {
+			if bet, err := cur.FromMajor(resp.GameState.Bet.Amount); err == nil && balance < bet {
+				resp.StopReason = StopInsufficientBalance
+				break
+			}
+		}
+
+		betID := fmt.Sprintf("%s-%d", req.BetID, round)
+		summary, gameState, biggestWin, err := rg.playAutoRound(env, resp.GameState, betID, client)
+		if err != nil && summary.Steps == 0 {
+			if round == 1 {
+				return AutoplayResponse{}, err
+			}
+			// Rounds already played stand; report them with the failure
+			stopAutoplay(&resp, round, err)
+			break
+		}
+
+		cost, win := cur.RoundMajor(summary.Cost), cur.RoundMajor(summary.Win)+cur.RoundMajor(summary.JackpotWin)
+		totalCost += cost
+		totalWin += win
+		balance += win - cost
+
+		summary.Round = round
+		summary.Balance = cur.ToMajor(balance)
+		resp.GameState = gameState
+		resp.Rounds = append(resp.Rounds, summary)
+		resp.RoundsPlayed = round
+
+		if onRound != nil {
+			if err := onRound(summary); err != nil {
+				return AutoplayResponse{}, err
+			}
+		}
+
+		// A step failed after the round's spin was paid; the round is reported with the failure
+		if err != nil {
+			stopAutoplay(&resp, round, err)
+			break
+		}
+
+		switch {
+		case singleWinLimit > 0 && biggestWin >= singleWinLimit:
+			resp.StopReason = StopSingleWinLimit
+		case winAbove > 0 && win > winAbove:
+			resp.StopReason = StopWinAbove
+		case stop.OnFreeSpins && summary.FreeSpinsTriggered:
+			resp.StopReason = StopFreeSpinsTriggered
+		case stop.OnLevelAdvance && summary.LevelAdvanced:
+			resp.StopReason = StopLevelAdvanced
+		case lossLimit > 0 && totalCost-totalWin >= lossLimit:
+			resp.StopReason = StopLossLimit
+		}
+		if resp.StopReason != "" {
+			break
+		}
+	}
+	if resp.StopReason == "" {
+		resp.StopReason = StopCompleted
+	}
+
+	resp.TotalCost = cur.ToMajor(totalCost)
+	resp.TotalWin = cur.ToMajor(totalWin)
+	resp.Balance = cur.ToMajor(balance)
+
+	log.Printf("Autoplay completed: session=%s rounds=%d/%d stopReason=%s totalCost=%s totalWin=%s",
+		env.Session.SessionID, resp.RoundsPlayed, req.Rounds, resp.StopReason, cur.Format(totalCost), cur.Format(totalWin))
+
+	return resp, nil
+}
+
+// stopAutoplay ends an autoplay run at a failed round, keeping the rounds already reported
+func stopAutoplay(resp *AutoplayResponse, round int, err error) {
+	log.Printf("Autoplay stopped at round %d: %v", round, err)
+	resp.StopReason = StopError
+	if responsible.IsBlock(err) {
+		resp.StopReason = StopResponsibleGaming
+	}
+	resp.Message = apierror.From(err).Message
+}
+
+// playAutoRound plays one spin and resolves its stage-cleared steps and cascades.
+// It returns the round summary, the settled game state and the biggest single step win.
+// When a step after the spin fails, it returns the unfinished round's summary and last game
+// state with the error; a summary with no steps means nothing was played.
+func (rg *RouteGroup) playAutoRound(env requestEnv, gameState GameState, betID string, client clientInfo) (AutoplayRoundSummary, GameState, money.Amount, error) {
+	cur := env.Currency
+	startMode := gameState.GameMode
+
+	spin, err := rg.playSpin(env, SpinRequest{GameState: gameState, BetID: betID}, client)
+	if err != nil {
+		return AutoplayRoundSummary{}, gameState, 0, err
+	}
+
+	win := cur.RoundMajor(spin.GameState.TotalWin)
+	progress := roundProgress{
+		Steps:              1,
+		Win:                win,
+		BiggestWin:         win,
+		JackpotWin:         jackpotTotal(spin.JackpotWins, cur),
+		FreeSpinsTriggered: startMode != "freeSpins" && spin.GameState.GameMode == "freeSpins",
+		MissionsCompleted:  completedMissions(nil, spin.Missions),
+	}
+	gs, err := rg.playRoundSteps(env, spin.GameState, spin.HasStageCleared, betID, client, &progress)
+
+	return AutoplayRoundSummary{
+		BetID:              betID,
+		Cost:               spin.TotalCost,
+		Win:                cur.ToMajor(progress.Win),
+		JackpotWin:         cur.ToMajor(progress.JackpotWin),
+		Steps:              progress.Steps,
+		Level:              gs.CurrentLevel,
+		GameMode:           gs.GameMode,
+		LevelAdvanced:      progress.LevelAdvanced,
+		FreeSpinsTriggered: progress.FreeSpinsTriggered,
+		MaxWinReached:      gs.MaxWinReached,
+		MissionsCompleted:  progress.MissionsCompleted,
+		Unfinished:         err != nil,
+	}, gs, progress.BiggestWin, err
+}
+
+// roundProgress accumulates the steps of a round played by the server
+type roundProgress struct {
+	Steps              int
+	Win                money.Amount
+	BiggestWin         money.Amount
+	JackpotWin         money.Amount
+	LevelAdvanced      bool
+	FreeSpinsTriggered bool
+	MissionsCompleted  []string
+}
+
+// playRoundSteps plays a round's remaining stage-cleared steps and cascades the way the client
+// would: stage-cleared symbols first, then cascades while connections remain. It returns the
+// settled game state, or the last state reached if a step fails.
+func (rg *RouteGroup) playRoundSteps(env requestEnv, gs GameState, hasStageCleared bool, betID string, client clientInfo, progress *roundProgress) (GameState, error) {
+	cur := env.Currency
+
+	for (hasStageCleared || gs.Cascading) && !gs.MaxWinReached {
+		if progress.Steps >= maxRoundSteps {
+			return gs, apierror.New(apierror.Internal, "Round did not settle")
+		}
+
+		previousMode := gs.GameMode
+		if hasStageCleared {
+			r, err := rg.playProcessStageCleared(env, ProcessStageClearedRequest{GameState: gs, BetID: betID}, client)
+			if err != nil {
+				return gs, err
+			}
+			gs = r.GameState
+			hasStageCleared = len(gs.StageClearedSymbols) > 0
+			progress.LevelAdvanced = progress.LevelAdvanced || r.LevelAdvanced
+			progress.JackpotWin += jackpotTotal(r.JackpotWins, cur)
+			progress.MissionsCompleted = completedMissions(progress.MissionsCompleted, r.Missions)
+		} else {
+			r, err := rg.playCascade(env, CascadeRequest{GameState: gs, BetID: betID}, client)
+			if err != nil {
+				return gs, err
+			}
+			gs = r.GameState
+			hasStageCleared = r.HasStageCleared
+			progress.JackpotWin += jackpotTotal(r.JackpotWins, cur)
+			progress.MissionsCompleted = completedMissions(progress.MissionsCompleted, r.Missions)
+		}
+
+		progress.Steps++
+		stepWin := cur.RoundMajor(gs.TotalWin)
+		progress.Win += stepWin
+		if stepWin > progress.BiggestWin {
+			progress.BiggestWin = stepWin
+		}
+		if previousMode != "freeSpins" && gs.GameMode == "freeSpins" {
+			progress.FreeSpinsTriggered = true
+		}
+	}
+	return gs, nil
+}
+
+// jackpotTotal adds up the jackpot wins of a step in minor units of cur
+func jackpotTotal(wins []JackpotWin, cur money.Currency) money.Amount {
+	var total money.Amount
+	for _, w := range wins {
+		total += cur.RoundMajor(w.Amount)
+	}
+	return total
+}
+
+// completedMissions appends the IDs of the missions a step completed
+func completedMissions(ids []string, updates []MissionProgress) []string {
+	for _, u := range updates {
+		if u.Completed {
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids
+}
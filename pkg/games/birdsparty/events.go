@@ -0,0 +1,55 @@
+package birdsparty
+
+// GameEventType is the kind of thing that happened in a game step
+type GameEventType string
+
+// Game events emitted by the game steps
+const (
+	EventClusterWin         GameEventType = "clusterWin"         // A paying connection of Symbol with Count symbols
+	EventStageCleared       GameEventType = "stageCleared"       // Count stage-cleared symbols of Symbol collected
+	EventLevelAdvanced      GameEventType = "levelAdvanced"      // Level was reached
+	EventFreeSpinsTriggered GameEventType = "freeSpinsTriggered" // Free spins were triggered
+)
+
+// GameEvent is one thing that happened in a game step, on the level it happened on
+type GameEvent struct {
+	Type   GameEventType `json:"type"`
+	Symbol Symbol        `json:"symbol,omitempty"`
+	Count  int           `json:"count,omitempty"`
+	Level  Level         `json:"level"`
+}
+
+// gameEvents collects the events of one game step in the order they happened
+type gameEvents []GameEvent
+
+// clusterWins adds an event for every paying connection
+func (e *gameEvents) clusterWins(level Level, connections []Connection) {
+	for _, connection := range connections {
+		*e = append(*e, GameEvent{Type: EventClusterWin, Symbol: connection.Symbol, Count: connection.Count, Level: level})
+	}
+}
+
+// stageCleared adds an event per symbol for the stage-cleared symbols collected
+func (e *gameEvents) stageCleared(level Level, symbols []StageClearedSymbol) {
+	counts := make(map[Symbol]int)
+	var order []Symbol
+	for _, s := range symbols {
+		if counts[s.Symbol] == 0 {
+			order = append(order, s.Symbol)
+		}
+		counts[s.Symbol]++
+	}
+	for _, symbol := range order {
+		*e = append(*e, GameEvent{Type: EventStageCleared, Symbol: symbol, Count: counts[symbol], Level: level})
+	}
+}
+
+// levelAdvanced adds an event for reaching a level
+func (e *gameEvents) levelAdvanced(level Level) {
+	*e = append(*e, GameEvent{Type: EventLevelAdvanced, Level: level})
+}
+
+// freeSpinsTriggered adds an event for triggering free spins
+func (e *gameEvents) freeSpinsTriggered(level Level) {
+	*e = append(*e, GameEvent{Type: EventFreeSpinsTriggered, Level: level})
+}
@@ -0,0 +1,388 @@
+package birdsparty
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Free round grant statuses
+const (
+	FreeRoundsActive    = "active"
+	FreeRoundsCompleted = "completed" // Every round has been played
+	FreeRoundsExpired   = "expired"
+	FreeRoundsCancelled = "cancelled"
+)
+
+// freeRoundsRetention is how long a grant is kept after it expires, for operator queries
+const freeRoundsRetention = 30 * 24 * time.Hour
+
+var (
+	// errGrantNotFound is returned for a grant ID the player was never granted
+	errGrantNotFound = errors.New("free round grant not found")
+	// errGrantUnavailable is returned when a grant has no round left to play
+	errGrantUnavailable = errors.New("free round grant is not available")
+	// errGrantConflict is returned when a grant ID is reused with different terms
+	errGrantConflict = errors.New("grant_id has already been used with different terms")
+)
+
+// freeRoundGrant is a number of rounds at a fixed bet granted to a player by the operator
+type freeRoundGrant struct {
+	id                 string
+	rounds             int
+	remaining          int
+	bet                money.Amount
+	cur                money.Currency
+	grantedAt          time.Time
+	expiresAt          time.Time
+	cancelled          bool
+	wageringMultiplier float64
+	win                money.Amount // Won by the grant's rounds, kept apart for wagering requirements
+}
+
+// status returns the grant's status at the given time
+func (g *freeRoundGrant) status(at time.Time) string {
+	switch {
+	case g.cancelled:
+		return FreeRoundsCancelled
+	case g.remaining == 0:
+		return FreeRoundsCompleted
+	case !at.Before(g.expiresAt):
+		return FreeRoundsExpired
+	default:
+		return FreeRoundsActive
+	}
+}
+
+// info describes the grant at the given time
+func (g *freeRoundGrant) info(at time.Time) FreeRoundGrant {
+	return FreeRoundGrant{
+		ID:                  g.id,
+		Status:              g.status(at),
+		Rounds:              g.rounds,
+		Remaining:           g.remaining,
+		BetAmount:           g.cur.ToMajor(g.bet),
+		Currency:            g.cur.Code,
+		GrantedAt:           g.grantedAt.Unix(),
+		ExpiresAt:           g.expiresAt.Unix(),
+		Win:                 g.cur.ToMajor(g.win),
+		WageringMultiplier:  g.wageringMultiplier,
+		WageringRequirement: g.cur.ToMajor(g.cur.Mul(g.win, g.wageringMultiplier)),
+	}
+}
+
+// FreeRounds holds the promotional free rounds operators grant their players. A free round is
+// a spin at the grant's bet that costs the player nothing; everything the round wins, including
+// its free spins, is credited to the grant so the operator can apply wagering requirements.
+// State is held in memory, so it is per instance and does not survive a restart.
+type FreeRounds struct {
+	mu      sync.Mutex
+	grants  map[string][]*freeRoundGrant // By player, in grant order
+	playing map[string]*freeRoundGrant   // Grant whose round each player is playing
+	now     func() time.Time
+}
+
+// NewFreeRounds creates an empty free round store
+func NewFreeRounds() *FreeRounds {
+	return &FreeRounds{
+		grants:  make(map[string][]*freeRoundGrant),
+		playing: make(map[string]*freeRoundGrant),
+		now:     time.Now,
+	}
+}
+
+// find returns the player's grant with the given ID; the caller holds f.mu
+func (f *FreeRounds) find(player, id string) *freeRoundGrant {
+	for _, g := range f.grants[player] {
+		if g.id == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// Grant gives a player free rounds. Granting an ID again with the same terms returns the
+// existing grant, so operators can safely retry; grants long past their expiry are dropped.
+func (f *FreeRounds) Grant(clientID, playerID string, g freeRoundGrant) (FreeRoundGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	player := playerKey(clientID, playerID)
+	if existing := f.find(player, g.id); existing != nil {
+		if existing.rounds != g.rounds || existing.bet != g.bet || existing.cur.Code != g.cur.Code ||
+			!existing.expiresAt.Equal(g.expiresAt) || existing.wageringMultiplier != g.wageringMultiplier {
+			return FreeRoundGrant{}, errGrantConflict
+		}
+		return existing.info(now), nil
+	}
+
+	var kept []*freeRoundGrant
+	for _, old := range f.grants[player] {
+		if now.Sub(old.expiresAt) < freeRoundsRetention || f.playing[player] == old {
+			kept = append(kept, old)
+		}
+	}
+	g.remaining = g.rounds
+	g.grantedAt = now
+	f.grants[player] = append(kept, &g)
+	return g.info(now), nil
+}
+
+// Cancel cancels a player's grant; rounds already played keep their winnings
+func (f *FreeRounds) Cancel(clientID, playerID, id string) (FreeRoundGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g := f.find(playerKey(clientID, playerID), id)
+	if g == nil {
+		return FreeRoundGrant{}, errGrantNotFound
+	}
+	g.cancelled = true
+	return g.info(f.now()), nil
+}
+
+// List returns every grant of a player, whatever its status
+func (f *FreeRounds) List(clientID, playerID string) []FreeRoundGrant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	grants := []FreeRoundGrant{}
+	for _, g := range f.grants[playerKey(clientID, playerID)] {
+		grants = append(grants, g.info(now))
+	}
+	return grants
+}
+
+// Active returns the player's grants that still have rounds to play in the given currency
+func (f *FreeRounds) Active(clientID, playerID string, cur money.Currency) []FreeRoundGrant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	grants := []FreeRoundGrant{}
+	for _, g := range f.grants[playerKey(clientID, playerID)] {
+		if g.cur.Code == cur.Code && g.status(now) == FreeRoundsActive {
+			grants = append(grants, g.info(now))
+		}
+	}
+	return grants
+}
+
+// consume takes one round from an active grant in the session currency, makes it the grant
+// the player is playing and returns its bet
+func (f *FreeRounds) consume(clientID, playerID, id string, cur money.Currency) (money.Amount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	g := f.find(player, id)
+	switch {
+	case g == nil:
+		return 0, errGrantNotFound
+	case g.cur.Code != cur.Code:
+		return 0, fmt.Errorf("%w: granted in %s, session is in %s", errGrantUnavailable, g.cur.Code, cur.Code)
+	}
+	if status := g.status(f.now()); status != FreeRoundsActive {
+		return 0, fmt.Errorf("%w: grant is %s", errGrantUnavailable, status)
+	}
+	g.remaining--
+	f.playing[player] = g
+	return g.bet, nil
+}
+
+// refund gives back a round consumed by a spin that failed
+func (f *FreeRounds) refund(clientID, playerID, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	if g := f.find(player, id); g != nil {
+		g.remaining++
+	}
+	delete(f.playing, player)
+}
+
+// endRound records that the player started a round that is not a free round
+func (f *FreeRounds) endRound(clientID, playerID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.playing, playerKey(clientID, playerID))
+}
+
+// credit adds a step's win to the grant whose round the player is playing and returns the
+// grant's ID, or "" when the player is not playing a free round
+func (f *FreeRounds) credit(clientID, playerID string, win money.Amount) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, ok := f.playing[playerKey(clientID, playerID)]
+	if !ok {
+		return ""
+	}
+	g.win += win
+	return g.id
+}
+
+// useFreeRound consumes a round of the grant a spin asks for and sets the spin's bet to the
+// grant's bet; free rounds start new rounds, so they cannot be played during free spins
+func (rg *RouteGroup) useFreeRound(env requestEnv, req *SpinRequest) error {
+	if rg.FreeRounds == nil {
+		return apierror.New(apierror.FeatureDisabled, "Free rounds are not enabled")
+	}
+	if req.GameState.GameMode == "freeSpins" {
+		return apierror.New(apierror.StepOutOfOrder, "Free rounds cannot be played during free spins")
+	}
+	bet, err := rg.FreeRounds.consume(env.Session.ClientID, env.Session.PlayerID, req.FreeRoundID, env.Currency)
+	if err != nil {
+		log.Printf("Free round %s refused for player %s: %v", req.FreeRoundID, env.Session.PlayerID, err)
+		if errors.Is(err, errGrantNotFound) {
+			return apierror.New(apierror.NotFound, fmt.Sprintf("Free round grant %q not found", req.FreeRoundID))
+		}
+		return apierror.New(apierror.FreeRoundUnavailable, err.Error())
+	}
+	req.GameState.Bet.Amount = env.Currency.ToMajor(bet)
+	req.GameState.Bet.Currency = env.Currency.Code
+	req.freeRound = true
+	return nil
+}
+
+// activeFreeRounds returns the session's remaining free round grants for a spin response
+func (rg *RouteGroup) activeFreeRounds(env requestEnv) []FreeRoundGrant {
+	if rg.FreeRounds == nil {
+		return []FreeRoundGrant{}
+	}
+	return rg.FreeRounds.Active(env.Session.ClientID, env.Session.PlayerID, env.Currency)
+}
+
+// FreeRoundsGrantHandler handles the /free-rounds/grant endpoint
+// Called by the operator (HMAC-signed) to grant a player free rounds at a fixed bet
+func (rg *RouteGroup) FreeRoundsGrantHandler(c *fiber.Ctx) error {
+	var req FreeRoundsGrantRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse free rounds grant request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	if rg.FreeRounds == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Free rounds are not enabled"))
+	}
+
+	cur, ok := money.Lookup(strings.ToUpper(strings.TrimSpace(req.Currency)))
+	if !ok {
+		return apierror.Write(c, apierror.New(apierror.UnsupportedCurrency, ""))
+	}
+	bet, err := cur.FromMajor(req.BetAmount)
+	switch {
+	case req.PlayerID == "" || req.GrantID == "":
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "player_id and grant_id are required"))
+	case req.Rounds <= 0:
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "rounds must be positive"))
+	case err != nil || bet <= 0:
+		return apierror.Write(c, apierror.New(apierror.InvalidBet, "bet_amount must be a positive amount in the currency"))
+	case !req.ExpiresAt.After(time.Now()):
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "expires_at must be in the future"))
+	case req.WageringMultiplier < 0:
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "wagering_multiplier must not be negative"))
+	}
+
+	// The rounds are spun at the grant's bet, so it must be on the operator's bet ladder and within the
+	// tenant's maximum bet; a grant no spin accepts could never be played
+	env, ok := rg.Environments[t.Environment]
+	if !ok {
+		return rejectTenant(c, req.ClientID, fmt.Errorf("no providers configured for environment %q", t.Environment))
+	}
+	gameSettings, err := env.Settings.GetSettings(t.ClientID, GameID, req.PlayerID)
+	if err != nil {
+		log.Printf("Failed to get game settings: %v", err)
+		return apierror.Write(c, apierror.Wrap(apierror.SettingsUnavailable, err))
+	}
+	if _, _, err := validateBetAmount(req.BetAmount, cur.Code, cur, gameSettings, 0, t.Limits.MaxBet); err != nil {
+		return apierror.Write(c, apierror.New(apierror.InvalidBet, err.Error()))
+	}
+
+	grant, err := rg.FreeRounds.Grant(t.ClientID, req.PlayerID, freeRoundGrant{
+		id:                 req.GrantID,
+		rounds:             req.Rounds,
+		bet:                bet,
+		cur:                cur,
+		expiresAt:          req.ExpiresAt,
+		wageringMultiplier: req.WageringMultiplier,
+	})
+	if err != nil {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, err.Error()))
+	}
+	log.Printf("Granted %d free rounds at %s to player %s of %s: grant=%s expires=%s",
+		req.Rounds, cur.Format(bet), req.PlayerID, t.ClientID, req.GrantID, req.ExpiresAt.UTC().Format(time.RFC3339))
+	return c.JSON(FreeRoundGrantResponse{Status: "success", Message: "", Grant: grant})
+}
+
+// FreeRoundsCancelHandler handles the /free-rounds/cancel endpoint
+// Called by the operator (HMAC-signed) to cancel a grant's remaining rounds
+func (rg *RouteGroup) FreeRoundsCancelHandler(c *fiber.Ctx) error {
+	var req FreeRoundsCancelRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse free rounds cancel request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	if rg.FreeRounds == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Free rounds are not enabled"))
+	}
+
+	grant, err := rg.FreeRounds.Cancel(t.ClientID, req.PlayerID, req.GrantID)
+	if err != nil {
+		return apierror.Write(c, apierror.New(apierror.NotFound, fmt.Sprintf("Free round grant %q not found", req.GrantID)))
+	}
+	log.Printf("Cancelled free round grant %s of player %s of %s with %d rounds left", req.GrantID, req.PlayerID, t.ClientID, grant.Remaining)
+	return c.JSON(FreeRoundGrantResponse{Status: "success", Message: "", Grant: grant})
+}
+
+// FreeRoundsQueryHandler handles the /free-rounds/query endpoint
+// Called by the operator (HMAC-signed) to read every grant of a player with its winnings
+func (rg *RouteGroup) FreeRoundsQueryHandler(c *fiber.Ctx) error {
+	var req FreeRoundsQueryRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse free rounds query request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	if rg.FreeRounds == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Free rounds are not enabled"))
+	}
+	if req.PlayerID == "" {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "player_id is required"))
+	}
+	return c.JSON(FreeRoundsResponse{Status: "success", Message: "", FreeRounds: rg.FreeRounds.List(t.ClientID, req.PlayerID)})
+}
+
+// FreeRoundsHandler handles the /free-rounds/birdsparty endpoint
+// Returns the player's free round grants that can be played in the session's currency
+func (rg *RouteGroup) FreeRoundsHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+	return c.JSON(FreeRoundsResponse{Status: "success", Message: "", FreeRounds: rg.activeFreeRounds(env)})
+}
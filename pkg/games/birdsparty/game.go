@@ -1,32 +1,33 @@
 package birdsparty
 
 import (
-	"fmt"
 	"log"
+	"maps"
 	"math"
-	"math/rand"
+	"slices"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
 )
 
-// round rounds a float64 to two decimal places
-func round(val float64) float64 {
-	return math.Round(val*100) / 100
+// WeightedRandomSymbol selects a symbol based on the level's base game weights
+func WeightedRandomSymbol(level Level, r Source) Symbol {
+	return pickWeighted(GetLevelSpecificWeights(level), r)
 }
 
-// WeightedRandomSymbol selects a symbol based on level-specific weights
-func WeightedRandomSymbol(level Level, r *rand.Rand) Symbol {
-	weights := GetLevelSpecificWeights(level)
-
+// pickWeighted draws one symbol from weights. The weights are walked in order,
+// so the same draw always selects the same symbol.
+func pickWeighted(weights []SymbolWeight, r Source) Symbol {
 	totalWeight := 0.0
-	for _, weight := range weights {
-		totalWeight += weight
+	for _, w := range weights {
+		totalWeight += w.Weight
 	}
 
 	roll := r.Float64() * totalWeight
 	currentWeight := 0.0
-	for symbol, weight := range weights {
-		currentWeight += weight
+	for _, w := range weights {
+		currentWeight += w.Weight
 		if roll <= currentWeight {
-			return symbol
+			return w.Symbol
 		}
 	}
 	return SymbolPurpleOwl // Fallback
@@ -44,33 +45,23 @@ func hasFreeGameSymbol(grid [][]string) bool {
 	return false
 }
 
-// Modified WeightedRandomSymbol to accept allowFreeGame argument
-// WeightedRandomSymbolWithControl now takes a forbidFreeGame argument (true = never allow free_game)
-func WeightedRandomSymbolWithControl(level Level, r *rand.Rand, forbidFreeGame bool) Symbol {
-	weights := GetLevelSpecificWeights(level)
+// WeightedRandomSymbolWithControl selects a symbol for a cell in column from the weight table of the level and game mode
+// forbidFreeGame = true never allows free_game
+func WeightedRandomSymbolWithControl(level Level, mode string, column int, r Source, forbidFreeGame bool) Symbol {
+	weights := GetSymbolWeights(level, mode, column)
 	if forbidFreeGame {
-		delete(weights, SymbolFreeGame)
-	}
-
-	totalWeight := 0.0
-	for _, weight := range weights {
-		totalWeight += weight
-	}
-
-	roll := r.Float64() * totalWeight
-	currentWeight := 0.0
-	for symbol, weight := range weights {
-		currentWeight += weight
-		if roll <= currentWeight {
-			return symbol
-		}
+		weights = slices.DeleteFunc(weights, func(w SymbolWeight) bool { return w.Symbol == SymbolFreeGame })
 	}
-	return SymbolPurpleOwl // Fallback
+	return pickWeighted(weights, r)
 }
 
 // GenerateGrid generates a grid of specified size with symbols for the given level
 // If forbidFreeGame is true, free_game symbol will never appear
-func GenerateGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
+// With reels, the grid is what a new stop of every reel strip shows and forbidFreeGame is left to the strips
+func GenerateGrid(level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) [][]string {
+	if reels != nil {
+		return reels.spin(r)
+	}
 	gridSize := level.GetGridSize()
 	grid := make([][]string, gridSize)
 	freeGamePlaced := false
@@ -78,7 +69,7 @@ func GenerateGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
 		grid[y] = make([]string, gridSize)
 		for x := 0; x < gridSize; x++ {
 			allowFreeGame := !freeGamePlaced && !forbidFreeGame
-			symbol := WeightedRandomSymbolWithControl(level, r, !allowFreeGame)
+			symbol := WeightedRandomSymbolWithControl(level, mode, x, r, !allowFreeGame)
 			if symbol == SymbolFreeGame {
 				freeGamePlaced = true
 			}
@@ -90,13 +81,13 @@ func GenerateGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
 
 // GenerateGridWithWin generates a grid that has potential connections (bird symbols only)
 // If forbidFreeGame is true, free_game symbol will never appear
-func GenerateGridWithWin(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
+func GenerateGridWithWin(level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) [][]string {
 	gridSize := level.GetGridSize()
 	log.Printf("Generating grid with win for level %d with grid size %dx%d", level, gridSize, gridSize)
 	maxAttempts := 100
 
 	for attempts := 0; attempts < maxAttempts; attempts++ {
-		grid := GenerateGrid(level, r, forbidFreeGame)
+		grid := GenerateGrid(level, mode, reels, r, forbidFreeGame)
 		// Check for bird symbol connections (ignore stage-cleared symbols)
 		connections := FindRegularConnections(grid, level)
 		if len(connections) > 0 {
@@ -105,18 +96,18 @@ func GenerateGridWithWin(level Level, r *rand.Rand, forbidFreeGame bool) [][]str
 	}
 
 	// If we can't generate a natural win, force one
-	return ForceWinGrid(level, r, forbidFreeGame)
+	return ForceWinGrid(level, mode, reels, r, forbidFreeGame)
 }
 
 // GenerateLossGrid generates a grid with no winning connections (bird symbols)
 // If forbidFreeGame is true, free_game symbol will never appear
-func GenerateLossGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
+func GenerateLossGrid(level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) [][]string {
 	gridSize := level.GetGridSize()
 	log.Printf("Generating loss grid for level %d with grid size %dx%d", level, gridSize, gridSize)
 	maxAttempts := 100
 
 	for attempts := 0; attempts < maxAttempts; attempts++ {
-		grid := GenerateGrid(level, r, forbidFreeGame)
+		grid := GenerateGrid(level, mode, reels, r, forbidFreeGame)
 		// Check for bird symbol connections (ignore stage-cleared symbols)
 		connections := FindRegularConnections(grid, level)
 		if len(connections) == 0 {
@@ -124,15 +115,22 @@ func GenerateLossGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string
 		}
 	}
 
-	// If we can't generate a natural loss, force one
+	// If we can't generate a natural loss, force one; reel strips pick the stops of one
+	if reels != nil {
+		return reels.forceLoss(level, r)
+	}
 	return ForceLossGrid(level, r, forbidFreeGame)
 }
 
 // ForceWinGrid creates a grid with guaranteed bird symbol connections
+// With reels, the stops of a winning grid are picked instead (see Reels.forceWin)
 // If forbidFreeGame is true, free_game symbol will never appear
-func ForceWinGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
+func ForceWinGrid(level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) [][]string {
+	if reels != nil {
+		return reels.forceWin(level, r)
+	}
 	gridSize := level.GetGridSize()
-	grid := GenerateGrid(level, r, forbidFreeGame)
+	grid := GenerateGrid(level, mode, nil, r, forbidFreeGame)
 	minConnection := level.GetMinConnection()
 
 	// Pick a random bird symbol
@@ -152,7 +150,7 @@ func ForceWinGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
 
 // ForceLossGrid creates a grid with no bird symbol connections
 // If forbidFreeGame is true, free_game symbol will never appear
-func ForceLossGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
+func ForceLossGrid(level Level, r Source, forbidFreeGame bool) [][]string {
 	gridSize := level.GetGridSize()
 	grid := make([][]string, gridSize)
 	birdSymbols := []Symbol{SymbolPurpleOwl, SymbolGreenOwl, SymbolYellowOwl, SymbolBlueOwl, SymbolRedOwl}
@@ -209,7 +207,7 @@ func ForceLossGrid(level Level, r *rand.Rand, forbidFreeGame bool) [][]string {
 
 // ProcessStageClearedSymbolsSurgical processes stage-cleared symbols with surgical precision
 // This preserves the grid structure and only affects the stage-cleared symbol positions
-func ProcessStageClearedSymbolsSurgical(gameState *GameState, stageClearedSymbols []StageClearedSymbol, level Level, r *rand.Rand) (bool, Level, Level) {
+func ProcessStageClearedSymbolsSurgical(gameState *GameState, stageClearedSymbols []StageClearedSymbol, level Level, r Source) (bool, Level, Level) {
 	if len(stageClearedSymbols) == 0 {
 		return false, gameState.CurrentLevel, gameState.CurrentLevel
 	}
@@ -220,7 +218,7 @@ func ProcessStageClearedSymbolsSurgical(gameState *GameState, stageClearedSymbol
 	RemoveStageClearedSymbolsSurgical(gameState.Grid, stageClearedSymbols)
 
 	// Apply gravity SURGICALLY - only affects columns with removed symbols
-	ApplyGravitySurgical(gameState.Grid, stageClearedSymbols, level, r, false)
+	ApplyGravitySurgical(gameState.Grid, stageClearedSymbols, level, gameState.GameMode, nil, r, false)
 
 	// Update stage progress
 	gameState.StageProgress += len(stageClearedSymbols)
@@ -241,7 +239,7 @@ func ProcessStageClearedSymbolsSurgical(gameState *GameState, stageClearedSymbol
 		gameState.StageProgress = excessProgress
 
 		// Regenerate grid with new level's size and symbols
-		gameState.Grid = GenerateGrid(newLevel, r, false) // No free game in new level
+		gameState.Grid = GenerateGrid(newLevel, gameState.GameMode, nil, r, false) // No free game in new level
 
 		levelAdvanced = true
 		log.Printf("Level advanced from %d to %d, excess progress: %d", oldLevel, newLevel, excessProgress)
@@ -266,7 +264,8 @@ func RemoveStageClearedSymbolsSurgical(grid [][]string, stageClearedSymbols []St
 
 // ApplyGravitySurgical applies gravity only to columns affected by stage-cleared symbol removal
 // If forbidFreeGame is true, free_game symbol will never appear
-func ApplyGravitySurgical(grid [][]string, stageClearedSymbols []StageClearedSymbol, level Level, r *rand.Rand, forbidFreeGame bool) []Position {
+// With reels, new symbols are fed from the strip above each column's stop
+func ApplyGravitySurgical(grid [][]string, stageClearedSymbols []StageClearedSymbol, level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) []Position {
 	gridSize := len(grid)
 	var newPositions []Position
 
@@ -276,10 +275,11 @@ func ApplyGravitySurgical(grid [][]string, stageClearedSymbols []StageClearedSym
 		affectedColumns[stageSymbol.Position.X] = true
 	}
 
-	log.Printf("Applying surgical gravity to columns: %v", getKeys(affectedColumns))
+	columns := getKeys(affectedColumns)
+	log.Printf("Applying surgical gravity to columns: %v", columns)
 
-	// Apply gravity only to affected columns
-	for x := range affectedColumns {
+	// Apply gravity only to affected columns, left to right
+	for _, x := range columns {
 		if x >= 0 && x < gridSize {
 			// Move existing symbols down
 			writePos := gridSize - 1
@@ -294,9 +294,17 @@ func ApplyGravitySurgical(grid [][]string, stageClearedSymbols []StageClearedSym
 			}
 
 			// Fill empty spaces at the top with new symbols
+			var fed []Symbol
+			if reels != nil {
+				fed = reels.feed(x, writePos+1)
+			}
 			for y := 0; y <= writePos; y++ {
-				allowFreeGame := !hasFreeGameSymbol(grid) && !forbidFreeGame
-				grid[y][x] = string(WeightedRandomSymbolWithControl(level, r, !allowFreeGame))
+				if reels != nil {
+					grid[y][x] = string(fed[y])
+				} else {
+					allowFreeGame := !hasFreeGameSymbol(grid) && !forbidFreeGame
+					grid[y][x] = string(WeightedRandomSymbolWithControl(level, mode, x, r, !allowFreeGame))
+				}
 				log.Printf("Generated new symbol %s at position (%d,%d) after surgical gravity", grid[y][x], x, y)
 				newPositions = append(newPositions, Position{X: x, Y: y})
 			}
@@ -306,21 +314,20 @@ func ApplyGravitySurgical(grid [][]string, stageClearedSymbols []StageClearedSym
 }
 
 // ApplySurgicalLoss attempts to remove connections while preserving the grid structure
-// Only modifies newly generated positions
+// Only modifies newly generated positions; with reels, the refilled columns get other stops instead
 // Returns true if surgical loss was successful, false if impossible
-func ApplySurgicalLoss(gameState *GameState, originalGrid [][]string, stageClearedSymbols []StageClearedSymbol, level Level, r *rand.Rand, newPositions []Position) bool {
-	// Build a set of allowed positions for modification
-	allowed := make(map[string]bool)
-	for _, pos := range newPositions {
-		key := fmt.Sprintf("%d,%d", pos.X, pos.Y)
-		allowed[key] = true
-	}
+func ApplySurgicalLoss(gameState *GameState, originalGrid [][]string, stageClearedSymbols []StageClearedSymbol, level Level, reels *Reels, r Source, newPositions []Position) bool {
+	// Positions allowed for modification, each once
+	allowed := uniquePositions(newPositions)
 
 	connections := FindRegularConnections(gameState.Grid, level)
 	if len(connections) == 0 {
 		// No connections to remove, surgical loss already achieved
 		return true
 	}
+	if reels != nil {
+		return applyReelLoss(gameState, reels, level, newPositions)
+	}
 
 	log.Printf("Attempting surgical loss on %d connections (only new positions)", len(connections))
 
@@ -334,23 +341,22 @@ func ApplySurgicalLoss(gameState *GameState, originalGrid [][]string, stageClear
 		}
 
 		// Try modifying a few allowed positions to break connections
+		// The positions are tried in an order drawn from r, a different one each attempt
 		modificationsCount := min(3, len(allowed))
 		modified := 0
+		shufflePositions(allowed, r)
 
-		for posKey := range allowed {
+		for _, pos := range allowed {
 			if modified >= modificationsCount {
 				break
 			}
-
-			// Parse position
-			var x, y int
-			fmt.Sscanf(posKey, "%d,%d", &x, &y)
+			x, y := pos.X, pos.Y
 
 			if x >= 0 && x < len(testGrid) && y >= 0 && y < len(testGrid[0]) {
 				originalSymbol := testGrid[y][x]
 				// Respect free spins mode - don't allow free game symbols during free spins
 				forbidFreeGame := gameState.GameMode == "freeSpins"
-				newSymbol := WeightedRandomSymbolWithControl(level, r, forbidFreeGame)
+				newSymbol := WeightedRandomSymbolWithControl(level, gameState.GameMode, x, r, forbidFreeGame)
 				testGrid[y][x] = string(newSymbol)
 
 				// Check if this breaks connections
@@ -371,13 +377,43 @@ func ApplySurgicalLoss(gameState *GameState, originalGrid [][]string, stageClear
 	return false
 }
 
-// Helper function to get keys from map
-func getKeys(m map[int]bool) []int {
-	keys := make([]int, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
+// applyReelLoss removes connections by picking other stops for the columns gravity refilled at
+// newPositions, so the refills still come from the strips; it returns false if no stops lose
+func applyReelLoss(gameState *GameState, reels *Reels, level Level, newPositions []Position) bool {
+	grid, ok := reels.restop(gameState.Grid, refilledRows(newPositions), connectedCells(level))
+	if !ok {
+		log.Printf("Surgical loss impossible: no stops of the refilled reels break the connections")
+		return false
+	}
+	gameState.Grid = grid
+	log.Printf("Surgical loss successful: refilled reels moved to stops %v", reels.Stops)
+	return true
+}
+
+// uniquePositions returns positions without duplicates, in their original order
+func uniquePositions(positions []Position) []Position {
+	seen := make(map[Position]bool)
+	var unique []Position
+	for _, pos := range positions {
+		if !seen[pos] {
+			seen[pos] = true
+			unique = append(unique, pos)
+		}
+	}
+	return unique
+}
+
+// shufflePositions shuffles positions in place with draws from r
+func shufflePositions(positions []Position, r Source) {
+	for i := len(positions) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		positions[i], positions[j] = positions[j], positions[i]
 	}
-	return keys
+}
+
+// getKeys returns the keys of a map in ascending order
+func getKeys(m map[int]bool) []int {
+	return slices.Sorted(maps.Keys(m))
 }
 
 // RemoveConnectionsSurgical removes connected symbols from the grid and returns affected positions
@@ -401,7 +437,8 @@ func RemoveConnectionsSurgical(grid [][]string, connections []Connection) []Posi
 
 // ApplyGravitySurgicalForCascade applies gravity only to columns affected by connection removal
 // If forbidFreeGame is true, free_game symbol will never appear
-func ApplyGravitySurgicalForCascade(grid [][]string, affectedPositions []Position, level Level, r *rand.Rand, forbidFreeGame bool) []Position {
+// With reels, new symbols are fed from the strip above each column's stop
+func ApplyGravitySurgicalForCascade(grid [][]string, affectedPositions []Position, level Level, mode string, reels *Reels, r Source, forbidFreeGame bool) []Position {
 	gridSize := len(grid)
 	var newPositions []Position
 
@@ -411,10 +448,11 @@ func ApplyGravitySurgicalForCascade(grid [][]string, affectedPositions []Positio
 		affectedColumns[pos.X] = true
 	}
 
-	log.Printf("Applying surgical cascade gravity to columns: %v", getKeys(affectedColumns))
+	columns := getKeys(affectedColumns)
+	log.Printf("Applying surgical cascade gravity to columns: %v", columns)
 
-	// Apply gravity only to affected columns
-	for x := range affectedColumns {
+	// Apply gravity only to affected columns, left to right
+	for _, x := range columns {
 		if x >= 0 && x < gridSize {
 			// Move existing symbols down
 			writePos := gridSize - 1
@@ -430,9 +468,17 @@ func ApplyGravitySurgicalForCascade(grid [][]string, affectedPositions []Positio
 			}
 
 			// Fill empty spaces at the top with new symbols
+			var fed []Symbol
+			if reels != nil {
+				fed = reels.feed(x, writePos+1)
+			}
 			for y := 0; y <= writePos; y++ {
-				allowFreeGame := !hasFreeGameSymbol(grid) && !forbidFreeGame
-				grid[y][x] = string(WeightedRandomSymbolWithControl(level, r, !allowFreeGame))
+				if reels != nil {
+					grid[y][x] = string(fed[y])
+				} else {
+					allowFreeGame := !hasFreeGameSymbol(grid) && !forbidFreeGame
+					grid[y][x] = string(WeightedRandomSymbolWithControl(level, mode, x, r, !allowFreeGame))
+				}
 				log.Printf("Generated new symbol %s at position (%d,%d) after cascade gravity", grid[y][x], x, y)
 				newPositions = append(newPositions, Position{X: x, Y: y})
 			}
@@ -442,21 +488,20 @@ func ApplyGravitySurgicalForCascade(grid [][]string, affectedPositions []Positio
 }
 
 // ApplySurgicalLossForCascade attempts to remove connections while preserving the grid structure for cascades
-// Only modifies newly generated positions
+// Only modifies newly generated positions; with reels, the refilled columns get other stops instead
 // Returns true if surgical loss was successful, false if impossible
-func ApplySurgicalLossForCascade(gameState *GameState, originalGrid [][]string, newPositions []Position, level Level, r *rand.Rand) bool {
-	// Build a set of allowed positions for modification
-	allowed := make(map[string]bool)
-	for _, pos := range newPositions {
-		key := fmt.Sprintf("%d,%d", pos.X, pos.Y)
-		allowed[key] = true
-	}
+func ApplySurgicalLossForCascade(gameState *GameState, originalGrid [][]string, newPositions []Position, level Level, reels *Reels, r Source) bool {
+	// Positions allowed for modification, each once
+	allowed := uniquePositions(newPositions)
 
 	connections := FindRegularConnections(gameState.Grid, level)
 	if len(connections) == 0 {
 		// No connections to remove, surgical loss already achieved
 		return true
 	}
+	if reels != nil {
+		return applyReelLoss(gameState, reels, level, newPositions)
+	}
 
 	log.Printf("Attempting surgical cascade loss on %d connections (only new positions)", len(connections))
 
@@ -470,23 +515,22 @@ func ApplySurgicalLossForCascade(gameState *GameState, originalGrid [][]string,
 		}
 
 		// Try modifying a few allowed positions to break connections
+		// The positions are tried in an order drawn from r, a different one each attempt
 		modificationsCount := min(4, len(allowed))
 		modified := 0
+		shufflePositions(allowed, r)
 
-		for posKey := range allowed {
+		for _, pos := range allowed {
 			if modified >= modificationsCount {
 				break
 			}
-
-			// Parse position
-			var x, y int
-			fmt.Sscanf(posKey, "%d,%d", &x, &y)
+			x, y := pos.X, pos.Y
 
 			if x >= 0 && x < len(testGrid) && y >= 0 && y < len(testGrid[0]) {
 				originalSymbol := testGrid[y][x]
 				// Respect free spins mode - don't allow free game symbols during free spins
 				forbidFreeGame := gameState.GameMode == "freeSpins"
-				newSymbol := WeightedRandomSymbolWithControl(level, r, forbidFreeGame)
+				newSymbol := WeightedRandomSymbolWithControl(level, gameState.GameMode, x, r, forbidFreeGame)
 				testGrid[y][x] = string(newSymbol)
 
 				// Check if this breaks connections
@@ -568,12 +612,11 @@ func FindRegularConnections(grid [][]string, level Level) []Connection {
 				positions := findConnectedPositions(grid, x, y, symbol, visited)
 
 				if len(positions) >= minConnection {
-					payout := calculatePayout(symbol, len(positions), level, 1) // Base multiplier
+					// Payout is priced per bet and currency by PayConnections
 					connections = append(connections, Connection{
 						Symbol:    symbol,
 						Positions: positions,
 						Count:     len(positions),
-						Payout:    payout,
 					})
 				}
 			}
@@ -618,20 +661,37 @@ func findConnectedPositions(grid [][]string, startX, startY int, symbol Symbol,
 	return positions
 }
 
-// calculatePayout calculates the payout for a connection
-func calculatePayout(symbol Symbol, count int, level Level, betMultiplier int) float64 {
-	paytable := GetPaytable(level)
-
-	if payoutMap, exists := paytable[symbol]; exists {
-		if payout, found := payoutMap[count]; found {
-			result := payout * 0.01 * float64(betMultiplier) // Denomination is 0.01
-			return round(result)
+// calculatePayout calculates the payout for a connection in minor units of cur from the size band
+// its count falls in. Band credits are whole, so the result is exact.
+func calculatePayout(paytable *Paytable, symbol Symbol, count int, level Level, betMultiplier int, cur money.Currency) money.Amount {
+	for _, band := range paytable.Levels[level][symbol] {
+		if band.contains(count) {
+			return money.Amount(math.Round(band.Credits)) * cur.Denomination * money.Amount(betMultiplier)
 		}
 	}
 
 	return 0
 }
 
+// PayConnections sets the payout of each connection at step cascadeLevel of the game mode's
+// cascade ladder, including the paytable modifiers but no free spin multiplier, and returns
+// their total in minor units of cur
+func (p *Paytable) PayConnections(connections []Connection, level Level, mode string, cascadeLevel, betMultiplier int, cur money.Currency) money.Amount {
+	cascadeMultiplier := p.Modifiers.cascadeMultiplier(mode, cascadeLevel)
+	multiplier := cascadeMultiplier * p.Modifiers.clusterBonus(len(connections))
+	var total money.Amount
+	for i, connection := range connections {
+		payout := calculatePayout(p, connection.Symbol, connection.Count, level, betMultiplier, cur)
+		if multiplier != 1 {
+			payout = cur.Mul(payout, multiplier)
+		}
+		connections[i].Payout = cur.ToMajor(payout)
+		connections[i].CascadeMultiplier = cascadeMultiplier
+		total += payout
+	}
+	return total
+}
+
 // RemoveConnections removes connected symbols from the grid and returns positions to fill
 func RemoveConnections(grid [][]string, connections []Connection) []Position {
 	var removedPositions []Position
@@ -649,7 +709,7 @@ func RemoveConnections(grid [][]string, connections []Connection) []Position {
 }
 
 // ApplyGravity makes symbols fall down to fill empty spaces (LEGACY - use surgical version when appropriate)
-func ApplyGravity(grid [][]string, level Level, r *rand.Rand) {
+func ApplyGravity(grid [][]string, level Level, mode string, r Source) {
 	gridSize := len(grid)
 
 	for x := 0; x < gridSize; x++ {
@@ -668,7 +728,7 @@ func ApplyGravity(grid [][]string, level Level, r *rand.Rand) {
 		// Fill empty spaces at the top with new symbols
 		for y := 0; y <= writePos; y++ {
 			allowFreeGame := !hasFreeGameSymbol(grid)
-			grid[y][x] = string(WeightedRandomSymbolWithControl(level, r, !allowFreeGame))
+			grid[y][x] = string(WeightedRandomSymbolWithControl(level, mode, x, r, !allowFreeGame))
 		}
 	}
 }
@@ -687,10 +747,12 @@ func CountFreeGameSymbols(grid [][]string) int {
 	return count
 }
 
+// FreeSpinMultipliers are the multipliers free spins can be awarded with
+var FreeSpinMultipliers = []float64{1.0, 1.5, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5.0}
+
 // GetRandomFreeSpinMultiplier returns a random multiplier between 1.0 and 5.0
-func GetRandomFreeSpinMultiplier(r *rand.Rand) float64 {
-	multipliers := []float64{1.0, 1.5, 2.0, 2.5, 3.0, 3.5, 4.0, 4.5, 5.0}
-	return multipliers[r.Intn(len(multipliers))]
+func GetRandomFreeSpinMultiplier(r Source) float64 {
+	return FreeSpinMultipliers[r.Intn(len(FreeSpinMultipliers))]
 }
 
 // AdvanceLevel advances to the next level or returns to level 1 after level 3
@@ -737,12 +799,13 @@ func UpdateGameStateForLevel(gameState *GameState, newLevel Level) {
 	gameState.CurrentLevel = newLevel
 	gameState.GridSize = newLevel.GetGridSize()
 	gameState.StageProgress = 0 // Reset progress for new level
+	gameState.CascadeLevel = 0  // A new level starts at the bottom of the cascade ladder
 
 	log.Printf("Advanced to Level %d with %dx%d grid", newLevel, gameState.GridSize, gameState.GridSize)
 }
 
 // ProcessStageClearedSymbols processes stage-cleared symbols and checks for level advancement (LEGACY VERSION)
-func ProcessStageClearedSymbols(gameState *GameState, stageClearedSymbols []StageClearedSymbol, level Level, r *rand.Rand) (bool, Level, Level) {
+func ProcessStageClearedSymbols(gameState *GameState, stageClearedSymbols []StageClearedSymbol, level Level, r Source) (bool, Level, Level) {
 	if len(stageClearedSymbols) == 0 {
 		return false, gameState.CurrentLevel, gameState.CurrentLevel
 	}
@@ -753,7 +816,7 @@ func ProcessStageClearedSymbols(gameState *GameState, stageClearedSymbols []Stag
 	RemoveStageClearedSymbols(gameState.Grid, stageClearedSymbols)
 
 	// Apply gravity after removing stage-cleared symbols
-	ApplyGravity(gameState.Grid, level, r)
+	ApplyGravity(gameState.Grid, level, gameState.GameMode, r)
 
 	// Update stage progress
 	gameState.StageProgress += len(stageClearedSymbols)
@@ -776,7 +839,7 @@ func ProcessStageClearedSymbols(gameState *GameState, stageClearedSymbols []Stag
 		// Regenerate grid with new level's size and symbols
 		// Respect free spins mode - don't allow free game symbols during free spins
 		forbidFreeGame := gameState.GameMode == "freeSpins"
-		gameState.Grid = GenerateGrid(newLevel, r, forbidFreeGame)
+		gameState.Grid = GenerateGrid(newLevel, gameState.GameMode, nil, r, forbidFreeGame)
 
 		levelAdvanced = true
 		log.Printf("Level advanced from %d to %d, excess progress: %d", oldLevel, newLevel, excessProgress)
@@ -787,6 +850,52 @@ func ProcessStageClearedSymbols(gameState *GameState, stageClearedSymbols []Stag
 	return levelAdvanced, oldLevel, oldLevel
 }
 
+// CapRoundWin limits a step win so the round total never exceeds winCap (0 means uncapped).
+// It returns the capped win and whether the cap would be reached by paying it.
+func CapRoundWin(roundWin, win, winCap money.Amount) (money.Amount, bool) {
+	if winCap <= 0 {
+		return win, false
+	}
+	remaining := winCap - roundWin
+	if remaining < 0 {
+		remaining = 0
+	}
+	if win >= remaining {
+		if win > remaining {
+			log.Printf("Win %d capped to %d minor units, round max win %d reached", win, remaining, winCap)
+		}
+		return remaining, true
+	}
+	return win, false
+}
+
+// AddRoundWin adds a (capped) step win to the round total and flags the round when the cap is hit.
+// The round total travels in the game state in major units and is re-read exactly in minor units.
+func AddRoundWin(gameState *GameState, win, winCap money.Amount, cur money.Currency) bool {
+	roundWin := cur.RoundMajor(gameState.RoundWin) + win
+	gameState.RoundWin = cur.ToMajor(roundWin)
+	if winCap > 0 && roundWin >= winCap {
+		gameState.MaxWinReached = true
+	}
+	return gameState.MaxWinReached
+}
+
+// EndRoundAtMaxWin terminates the round once the max win is reached:
+// no further cascades, stage-cleared processing or free spins are played
+func EndRoundAtMaxWin(gameState *GameState) {
+	gameState.Cascading = false
+	gameState.CascadeCount = 0
+	gameState.StageClearedSymbols = []StageClearedSymbol{}
+	if gameState.GameMode == "freeSpins" {
+		log.Printf("Free Spins ended early: max win reached")
+	}
+	gameState.GameMode = "base"
+	gameState.FreeSpins.Remaining = 0
+	gameState.FreeSpins.TotalAwarded = 0
+	gameState.FreeSpins.Multiplier = 1.0
+	log.Printf("Round ended at max win: roundWin=%.2f", gameState.RoundWin)
+}
+
 // ValidateGridDimensions ensures grid matches expected size for level
 func ValidateGridDimensions(grid [][]string, level Level) bool {
 	expectedSize := level.GetGridSize()
@@ -802,7 +911,7 @@ func ValidateGridDimensions(grid [][]string, level Level) bool {
 }
 
 // CleanupInvalidSymbols removes any invalid symbols that don't belong to current level
-func CleanupInvalidSymbols(grid [][]string, level Level, r *rand.Rand) {
+func CleanupInvalidSymbols(grid [][]string, level Level, r Source) {
 	gridSize := len(grid)
 	levelStageClearedSymbol := level.GetStageClearedSymbol()
 
@@ -813,8 +922,8 @@ func CleanupInvalidSymbols(grid [][]string, level Level, r *rand.Rand) {
 			// If it's a stage-cleared symbol that doesn't belong to current level, replace it
 			if IsStageClearedSymbol(symbol) && symbol != levelStageClearedSymbol {
 				// Note: This function doesn't have access to gameState, so we can't check game mode
-				// For now, we'll use the basic symbol generation - this function is rarely used
-				newSymbol := WeightedRandomSymbol(level, r)
+				// For now, we'll use the base game weights - this function is rarely used
+				newSymbol := WeightedRandomSymbolWithControl(level, "base", x, r, false)
 				grid[y][x] = string(newSymbol)
 				log.Printf("Replaced invalid stage-cleared symbol %s with %s at (%d,%d)",
 					symbol, newSymbol, x, y)
@@ -0,0 +1,218 @@
+package birdsparty
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/rng"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+)
+
+// usd is the currency the game tests play in
+var usd, _ = money.Lookup("USD")
+
+// testEnv returns the environment of a USD session whose RNG service always answers outcome
+// and whose operator settings are s
+func testEnv(outcome string, s settings.Settings) requestEnv {
+	return requestEnv{
+		Session:  session.Claims{SessionID: "session_1", ClientID: "operator_test", GameID: GameID, PlayerID: "player_1", Currency: "USD"},
+		Currency: usd,
+		RNG:      rng.NewScriptedOutcome([]string{outcome}, true),
+		Settings: &settings.StaticProvider{Settings: s},
+	}
+}
+
+// seededTrace records a step that draws from a generator seeded with seed
+func seededTrace(step RoundStep, gameState GameState, seed int64) *stepTrace {
+	t := &stepTrace{replay: StepReplay{Step: step, GameState: cloneGameState(gameState)}}
+	t.source = &recordingSource{src: rand.New(rand.NewSource(seed)), draws: &t.replay.Draws}
+	return t
+}
+
+// newTestGame returns the game state of a new player betting bet
+func newTestGame(bet float64) GameState {
+	gs := InitializeGameState()
+	gs.Bet.Amount = bet
+	return gs
+}
+
+// cappedAt returns operator settings that cap a round's win at maxWin
+func cappedAt(maxWin float64) settings.Settings {
+	return settings.Settings{RTP: 96, MaxWin: maxWin}
+}
+
+// Tests below search seeds for a step that pays, so they hold whatever the engine's exact draws are
+
+func TestCapRoundWin(t *testing.T) {
+	tests := []struct {
+		name                  string
+		roundWin, win, winCap money.Amount
+		want                  money.Amount
+		reached               bool
+	}{
+		{"uncapped", 500, 1000, 0, 1000, false},
+		{"below the cap", 100, 50, 200, 50, false},
+		{"exactly reaches the cap", 150, 50, 200, 50, true},
+		{"capped", 150, 80, 200, 50, true},
+		{"cap already passed", 250, 10, 200, 0, true},
+		{"no win below the cap", 100, 0, 200, 0, false},
+	}
+	for _, tt := range tests {
+		got, reached := CapRoundWin(tt.roundWin, tt.win, tt.winCap)
+		if got != tt.want || reached != tt.reached {
+			t.Errorf("%s: CapRoundWin(%d, %d, %d) = %d, %v, want %d, %v", tt.name, tt.roundWin, tt.win, tt.winCap, got, reached, tt.want, tt.reached)
+		}
+	}
+}
+
+func TestAddRoundWin(t *testing.T) {
+	gs := newTestGame(0.1)
+	for i := 0; i < 10; i++ {
+		if AddRoundWin(&gs, 10, 0, usd) {
+			t.Fatal("an uncapped round reached its max win")
+		}
+	}
+	// Ten steps of 0.10 add up to exactly 1.00, without float drift
+	if gs.RoundWin != 1 {
+		t.Fatalf("roundWin = %v, want 1", gs.RoundWin)
+	}
+	if AddRoundWin(&gs, 9, 110, usd) {
+		t.Fatal("roundWin 1.09 reached a 1.10 cap")
+	}
+	if !AddRoundWin(&gs, 1, 110, usd) || gs.RoundWin != 1.1 {
+		t.Fatalf("roundWin = %v, maxWinReached = %v, want 1.1 and the cap reached", gs.RoundWin, gs.MaxWinReached)
+	}
+}
+
+func TestEndRoundAtMaxWin(t *testing.T) {
+	gs := newTestGame(0.1)
+	gs.CurrentLevel, gs.GridSize, gs.StageProgress = Level2, Level2.GetGridSize(), 7
+	gs.GameMode = "freeSpins"
+	gs.FreeSpins.Remaining, gs.FreeSpins.TotalAwarded, gs.FreeSpins.Multiplier = 4, FreeSpinsAwarded, 2.5
+	gs.Cascading, gs.CascadeCount, gs.CascadeLevel = true, 3, 3
+	gs.StageClearedSymbols = []StageClearedSymbol{{Symbol: SymbolHoneyPot}}
+
+	EndRoundAtMaxWin(&gs)
+	switch {
+	case gs.Cascading || gs.CascadeCount != 0 || len(gs.StageClearedSymbols) != 0:
+		t.Errorf("round left steps to play: cascading=%v cascadeCount=%d stageClearedSymbols=%d", gs.Cascading, gs.CascadeCount, len(gs.StageClearedSymbols))
+	case gs.GameMode != "base" || gs.FreeSpins.Remaining != 0 || gs.FreeSpins.TotalAwarded != 0 || gs.FreeSpins.Multiplier != 1:
+		t.Errorf("free spins not ended: mode=%s freeSpins=%+v", gs.GameMode, gs.FreeSpins)
+	case gs.CurrentLevel != Level2 || gs.StageProgress != 7:
+		t.Errorf("level progress changed to level %d, progress %d", gs.CurrentLevel, gs.StageProgress)
+	}
+}
+
+// checkRoundEnded checks that a step ended its round at the max win cap
+func checkRoundEnded(t *testing.T, gs GameState, winCap money.Amount) {
+	t.Helper()
+	switch {
+	case !gs.MaxWinReached:
+		t.Error("maxWinReached is false")
+	case usd.RoundMajor(gs.RoundWin) != winCap:
+		t.Errorf("roundWin = %v, want the cap %s", gs.RoundWin, usd.Format(winCap))
+	case gs.Cascading || gs.CascadeCount != 0 || len(gs.StageClearedSymbols) != 0:
+		t.Errorf("round left steps to play: cascading=%v cascadeCount=%d stageClearedSymbols=%d", gs.Cascading, gs.CascadeCount, len(gs.StageClearedSymbols))
+	case gs.GameMode != "base" || gs.FreeSpins.Remaining != 0:
+		t.Errorf("free spins not ended: mode=%s remaining=%d", gs.GameMode, gs.FreeSpins.Remaining)
+	}
+}
+
+func TestMaxWinReachedMidCascade(t *testing.T) {
+	rg := &RouteGroup{}
+	for seed := int64(1); seed <= 500; seed++ {
+		spin, err := rg.spin(testEnv("win", settings.Settings{RTP: 96}), SpinRequest{GameState: newTestGame(0.1), BetID: "bet_1"}, clientInfo{}, seededTrace(StepSpin, newTestGame(0.1), seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !spin.GameState.Cascading || spin.HasStageCleared {
+			continue
+		}
+
+		// The cap is one cent above what the spin won, so the first paying cascade reaches it
+		winCap := usd.RoundMajor(spin.GameState.RoundWin) + 1
+		env := testEnv("win", cappedAt(usd.ToMajor(winCap)))
+		resp, err := rg.cascade(env, CascadeRequest{GameState: spin.GameState, BetID: "bet_1"}, clientInfo{}, seededTrace(StepCascade, spin.GameState, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GameState.TotalWin == 0 {
+			continue
+		}
+
+		if !resp.MaxWinReached || resp.HasStageCleared || resp.GameState.TotalWin != 0.01 {
+			t.Errorf("cascade maxWinReached=%v hasStageCleared=%v totalWin=%v, want the cap reached by a 0.01 win", resp.MaxWinReached, resp.HasStageCleared, resp.GameState.TotalWin)
+		}
+		checkRoundEnded(t, resp.GameState, winCap)
+		if _, err := rg.cascade(env, CascadeRequest{GameState: resp.GameState, BetID: "bet_1"}, clientInfo{}, seededTrace(StepCascade, resp.GameState, seed)); !apierror.Is(err, apierror.RoundEnded) {
+			t.Errorf("cascade after the max win returned %v, want %s", err, apierror.RoundEnded)
+		}
+		return
+	}
+	t.Fatal("no seed gave a spin and a paying cascade")
+}
+
+func TestMaxWinReachedInFreeSpins(t *testing.T) {
+	rg := &RouteGroup{}
+	gs := newTestGame(0.1)
+	gs.GameMode = "freeSpins"
+	gs.FreeSpins.Remaining, gs.FreeSpins.TotalAwarded, gs.FreeSpins.Multiplier = 5, FreeSpinsAwarded, 2
+	gs.RoundWin = 1.5
+
+	// Free spins continue the round's running win, so the cap counts what the round won before them
+	env := testEnv("win", cappedAt(1.51))
+	for seed := int64(1); seed <= 500; seed++ {
+		resp, err := rg.spin(env, SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, seededTrace(StepSpin, gs, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GameState.TotalWin == 0 {
+			continue
+		}
+
+		if resp.TotalCost != 0 || resp.GameState.TotalWin != 0.01 || !resp.MaxWinReached {
+			t.Errorf("free spin totalCost=%v totalWin=%v maxWinReached=%v, want a free 0.01 win reaching the cap", resp.TotalCost, resp.GameState.TotalWin, resp.MaxWinReached)
+		}
+		checkRoundEnded(t, resp.GameState, 151)
+		if resp.GameState.FreeSpins.TotalAwarded != 0 || resp.GameState.FreeSpins.Multiplier != 1 {
+			t.Errorf("freeSpins = %+v after the max win, want them reset", resp.GameState.FreeSpins)
+		}
+		return
+	}
+	t.Fatal("no seed gave a paying free spin")
+}
+
+func TestMaxWinReachedOnLevelAdvance(t *testing.T) {
+	rg := &RouteGroup{}
+
+	// One stage-cleared symbol short of the target, so processing the grid's symbols advances the level
+	gs := stageClearedGame(StageProgressTarget - 1)
+	gs.RoundWin = 0.5
+
+	env := testEnv("win", cappedAt(0.51))
+	for seed := int64(1); seed <= 500; seed++ {
+		resp, err := rg.processStageCleared(env, ProcessStageClearedRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, seededTrace(StepProcessStageCleared, gs, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.LevelAdvanced || resp.NewLevel != Level2 {
+			t.Fatalf("levelAdvanced=%v newLevel=%d, want level 2", resp.LevelAdvanced, resp.NewLevel)
+		}
+		if resp.GameState.TotalWin == 0 {
+			continue
+		}
+
+		if resp.GameState.TotalWin != 0.01 || !resp.MaxWinReached {
+			t.Errorf("totalWin=%v maxWinReached=%v, want a 0.01 win reaching the cap", resp.GameState.TotalWin, resp.MaxWinReached)
+		}
+		checkRoundEnded(t, resp.GameState, 51)
+		if resp.GameState.CurrentLevel != Level2 || resp.GameState.GridSize != Level2.GetGridSize() {
+			t.Errorf("level %d with grid size %d after the max win, want the advanced level kept", resp.GameState.CurrentLevel, resp.GameState.GridSize)
+		}
+		return
+	}
+	t.Fatal("no seed gave a paying grid on the new level")
+}
@@ -3,9 +3,13 @@ package birdsparty
 import (
 	"fmt"
 	"log"
-	"math/rand"
-	"time"
+	"slices"
+	"strings"
 
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/audit"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -13,24 +17,92 @@ import (
 // Generates grid with potential wins, identifies stage-cleared symbols
 // Checks for regular bird symbol connections to determine cascading
 func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
-	rngClient, settingsClient := rg.getClientsForRequest(c)
-
 	var req SpinRequest
 	if err := c.BodyParser(&req); err != nil {
 		log.Printf("Failed to parse request body: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid request body",
-		})
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	// Resolve the tenant and its environment from the session token
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	resp, err := rg.playSpin(env, req, clientInfoFrom(c))
+	if err != nil {
+		return apierror.Write(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// playSpin runs one spin for a resolved session; it is shared by the REST and WebSocket transports
+func (rg *RouteGroup) playSpin(env requestEnv, req SpinRequest, client clientInfo) (SpinResponse, error) {
+	if err := rg.beginStep(env, client, req.BetID, StepSpin, &req.GameState); err != nil {
+		return SpinResponse{}, err
+	}
+
+	// A free round plays at the grant's bet; any other spin outside free spins ends the player's free round
+	if req.FreeRoundID != "" {
+		if err := rg.useFreeRound(env, &req); err != nil {
+			rg.endStep(env, req.BetID, req.GameState, err)
+			return SpinResponse{}, err
+		}
+	} else if rg.FreeRounds != nil && req.GameState.GameMode != "freeSpins" {
+		rg.FreeRounds.endRound(env.Session.ClientID, env.Session.PlayerID)
+	}
+
+	trace := newStepTrace(StepSpin, req.GameState)
+	trace.replay.FreeRound = req.freeRound
+	resp, err := rg.spin(env, req, client, trace)
+	if err != nil && req.freeRound {
+		rg.FreeRounds.refund(env.Session.ClientID, env.Session.PlayerID, req.FreeRoundID)
 	}
+	rg.endStep(env, req.BetID, resp.GameState, err)
+	if err != nil {
+		return resp, err
+	}
+	if req.freeRound {
+		resp.FreeRoundID = req.FreeRoundID
+	}
+	resp.FreeRounds = rg.activeFreeRounds(env)
+	return resp, nil
+}
+
+// spin plays a spin whose place in the round order has been checked
+func (rg *RouteGroup) spin(env requestEnv, req SpinRequest, client clientInfo, trace *stepTrace) (SpinResponse, error) {
+	rngClient, settingsClient := trace.providers(env.RNG, env.Settings)
+
+	// Identity comes from the session, never from the request body
+	req.ClientID, req.GameID, req.PlayerID = env.Session.ClientID, env.Session.GameID, env.Session.PlayerID
 
 	// Validate request
-	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID, req.GameState.Bet.Amount); err != nil {
+	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID); err != nil {
+		log.Printf("Request validation failed: %v", err)
+		return SpinResponse{}, apierror.New(apierror.InvalidRequest, err.Error())
+	}
+
+	// Load the operator's settings (RTP, bet ladder, win cap)
+	gameSettings, err := settingsClient.GetSettings(req.ClientID, req.GameID, req.PlayerID)
+	if err != nil {
+		log.Printf("Failed to get game settings: %v", err)
+		return SpinResponse{}, apierror.Wrap(apierror.SettingsUnavailable, err)
+	}
+	cur := env.Currency
+	bet, betMultiplier, err := validateBetAmount(req.GameState.Bet.Amount, req.GameState.Bet.Currency, cur, gameSettings, env.Session.MinBet, env.maxBet())
+	if err != nil {
 		log.Printf("Request validation failed: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": err.Error(),
-		})
+		return SpinResponse{}, apierror.New(apierror.InvalidBet, err.Error())
+	}
+
+	// Responsible-gaming rules are evaluated before the bet is processed; free spins and free rounds stake
+	// nothing. The game mode comes from the server's round state (see spinState), never from the client.
+	stake := bet
+	if req.GameState.GameMode == "freeSpins" || req.freeRound {
+		stake = 0
+	}
+	if err := rg.checkResponsibleGaming(env, stake); err != nil {
+		log.Printf("Spin blocked by responsible gaming for player %s: %v", req.PlayerID, err)
+		return SpinResponse{}, err
 	}
 
 	// Initialize game state if needed
@@ -48,15 +120,23 @@ func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
 		log.Printf("Corrected grid size to %d for level %d", expectedGridSize, req.GameState.CurrentLevel)
 	}
 
-	// Create rand instance
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// Every random draw of the step is recorded for replay
+	r := trace.source
 
-	// Set bet multiplier
-	req.GameState.Bet.Multiplier = BetAmountToMultiplier[req.GameState.Bet.Amount]
+	// Set the validated bet; the multiplier is always derived from the amount, never trusted from the client
+	req.GameState.Bet.Amount = cur.ToMajor(bet)
+	req.GameState.Bet.Currency = cur.Code
+	req.GameState.Bet.Multiplier = betMultiplier
+
+	// Math variants with reel strips draw the grid from new reel stops
+	reels, err := rg.reelsFor(env.Tenant, req.GameState, true)
+	if err != nil {
+		return SpinResponse{}, err
+	}
 
 	// Generate grid with potential bird symbol connections
 	forbidFreeGame := req.GameState.GameMode == "freeSpins"
-	req.GameState.Grid = GenerateGridWithWin(req.GameState.CurrentLevel, r, forbidFreeGame)
+	req.GameState.Grid = GenerateGridWithWin(req.GameState.CurrentLevel, req.GameState.GameMode, reels, r, forbidFreeGame)
 
 	// Find stage-cleared symbols (do NOT remove them yet)
 	stageClearedSymbols := FindStageClearedSymbols(req.GameState.Grid, req.GameState.CurrentLevel)
@@ -65,69 +145,72 @@ func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
 	// Check for regular bird symbol connections to determine if cascading will happen
 	connections := FindRegularConnections(req.GameState.Grid, req.GameState.CurrentLevel)
 
-	// Calculate total winnings
-	totalWinnings := 0.0
-	for i, connection := range connections {
-		payout := calculatePayout(connection.Symbol, connection.Count, req.GameState.CurrentLevel, req.GameState.Bet.Multiplier)
-		connections[i].Payout = payout // base payout, no multiplier
-		totalWinnings += payout
-	}
+	// Calculate total winnings in minor units; a spin starts at the bottom of the cascade ladder
+	req.GameState.CascadeLevel = 0
+	totalWinnings := rg.paytable().PayConnections(connections, req.GameState.CurrentLevel, req.GameState.GameMode, req.GameState.CascadeLevel, betMultiplier, cur)
 	if req.GameState.GameMode == "freeSpins" {
-		totalWinnings *= req.GameState.FreeSpins.Multiplier
+		totalWinnings = cur.Mul(totalWinnings, req.GameState.FreeSpins.Multiplier)
 	}
-	totalWinnings = round(totalWinnings)
+
+	// A spin outside free spins starts a new round for the max win cap
+	if req.GameState.GameMode != "freeSpins" {
+		req.GameState.RoundWin = 0
+		req.GameState.MaxWinReached = false
+	}
+	winCap := roundWinCap(gameSettings, bet, cur, rg.maxWinMultiplierFor(env.Tenant))
+	totalWinnings, _ = CapRoundWin(cur.RoundMajor(req.GameState.RoundWin), totalWinnings, winCap)
 
 	// Get RTP and call RNG for bird symbol connections
 	if len(connections) > 0 {
-		rtp, err := settingsClient.GetRTP(req.ClientID, req.GameID, req.PlayerID)
-		if err != nil {
-			log.Printf("Failed to get RTP: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to retrieve game settings",
-			})
-		}
-
 		// Call RNG
-		payoutMultiplier := totalWinnings / req.GameState.Bet.Amount
-		ip := c.IP()
-		userAgent := c.Get("User-Agent")
+		payoutMultiplier := float64(totalWinnings) / float64(bet)
+		ip := client.IP
+		userAgent := client.UserAgent
 
 		log.Printf("✅IP: %v", ip)
 		log.Printf("✅User-Agent: %v", userAgent)
-		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, rtp, payoutMultiplier, req.GameState.Bet.Amount, ip, userAgent)
+		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, gameSettings.RTP, payoutMultiplier, cur.ToMajor(bet), cur.Code, ip, userAgent)
 		if err != nil {
 			log.Printf("Failed to call RNG API: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to determine outcome",
-			})
+			return SpinResponse{}, apierror.Wrap(apierror.RNGUnavailable, err)
 		}
 
 		// Adjust outcome based on RNG
 		if rngResp.PrefOutcome == "loss" {
 			log.Printf("RNG determined a loss outcome")
 			forbidFreeGame := req.GameState.GameMode == "freeSpins"
-			req.GameState.Grid = GenerateLossGrid(req.GameState.CurrentLevel, r, forbidFreeGame)
+			req.GameState.Grid = GenerateLossGrid(req.GameState.CurrentLevel, req.GameState.GameMode, reels, r, forbidFreeGame)
 
 			// Re-find stage-cleared symbols in loss grid
 			stageClearedSymbols = FindStageClearedSymbols(req.GameState.Grid, req.GameState.CurrentLevel)
 			req.GameState.StageClearedSymbols = stageClearedSymbols
 
-			connections = nil
+			// Reel strips can lack losing stops; the grid they show then pays as it is
+			connections = FindRegularConnections(req.GameState.Grid, req.GameState.CurrentLevel)
 			totalWinnings = 0
+			if len(connections) > 0 {
+				log.Printf("⚠️  RNG BYPASS: no losing reel stops found - paying the grid the strips show")
+				totalWinnings = rg.paytable().PayConnections(connections, req.GameState.CurrentLevel, req.GameState.GameMode, req.GameState.CascadeLevel, betMultiplier, cur)
+				if req.GameState.GameMode == "freeSpins" {
+					totalWinnings = cur.Mul(totalWinnings, req.GameState.FreeSpins.Multiplier)
+				}
+				totalWinnings, _ = CapRoundWin(cur.RoundMajor(req.GameState.RoundWin), totalWinnings, winCap)
+			}
 		}
 	}
 
 	// Reset cascade count for new spin
+	maxWinReached := AddRoundWin(&req.GameState, totalWinnings, winCap, cur)
+	req.GameState.Reels = reels.state()
 	req.GameState.CascadeCount = 0
-	req.GameState.TotalWin = totalWinnings
+	req.GameState.TotalWin = cur.ToMajor(totalWinnings)
 	req.GameState.LastConnections = connections
 	req.GameState.Cascading = len(connections) > 0
 
 	// Check for free game symbols
 	freeGameCount := CountFreeGameSymbols(req.GameState.Grid)
-	if req.GameState.GameMode == "base" && freeGameCount > 0 {
+	freeSpinsTriggered := req.GameState.GameMode == "base" && freeGameCount > 0
+	if freeSpinsTriggered {
 		req.GameState.GameMode = "freeSpins"
 		req.GameState.FreeSpins.Remaining = FreeSpinsAwarded
 		req.GameState.FreeSpins.TotalAwarded = FreeSpinsAwarded
@@ -149,12 +232,14 @@ func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
 		}
 	}
 
-	// Calculate total cost
-	var totalCost float64
-	if req.GameState.GameMode == "freeSpins" {
-		totalCost = 0
-	} else {
-		totalCost = req.GameState.Bet.Amount
+	// The spin costs its stake, from the mode it was played in: the spin that triggers free spins is
+	// paid and the last free spin is not
+	totalCost := stake
+
+	// Hitting the max win ends the round, including any remaining free spins
+	if maxWinReached {
+		EndRoundAtMaxWin(&req.GameState)
+		stageClearedSymbols = req.GameState.StageClearedSymbols
 	}
 
 	// Determine if we have stage-cleared symbols
@@ -163,15 +248,28 @@ func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
 	log.Printf("Spin completed: level=%d, gridSize=%dx%d, stageClearedSymbols=%d, hasStageCleared=%v, cascading=%v",
 		req.GameState.CurrentLevel, req.GameState.GridSize, req.GameState.GridSize,
 		len(stageClearedSymbols), hasStageCleared, req.GameState.Cascading)
+	jackpot := rg.playJackpots(env, req.GameState, totalCost, connections, trace)
+	rg.scoreTournaments(env, req.GameState, bet, totalCost, 0)
+	rg.recordStep("spin", env, req.BetID, req.GameState, bet, totalCost, totalWinnings, jackpot, trace)
+
+	var events gameEvents
+	events.clusterWins(req.GameState.CurrentLevel, connections)
+	if freeSpinsTriggered {
+		events.freeSpinsTriggered(req.GameState.CurrentLevel)
+	}
+	missions := rg.trackMissions(env, events)
 
-	return c.JSON(SpinResponse{
+	return SpinResponse{
 		Status:              "success",
 		Message:             "",
 		GameState:           req.GameState,
 		StageClearedSymbols: stageClearedSymbols,
 		HasStageCleared:     hasStageCleared,
-		TotalCost:           totalCost,
-	})
+		TotalCost:           cur.ToMajor(totalCost),
+		MaxWinReached:       maxWinReached,
+		JackpotWins:         jackpot.wins,
+		Missions:            missions,
+	}, nil
 }
 
 // ProcessStageClearedHandler handles the /process-stage-cleared/birdsparty endpoint
@@ -179,37 +277,82 @@ func (rg *RouteGroup) SpinHandler(c *fiber.Ctx) error {
 // AND checks for regular bird symbol connections in the new grid
 // FIXED: Now preserves grid structure and uses surgical loss approach
 func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
-	rngClient, settingsClient := rg.getClientsForRequest(c)
-
 	var req ProcessStageClearedRequest
 	if err := c.BodyParser(&req); err != nil {
 		log.Printf("Failed to parse request body: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid request body",
-		})
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	// Resolve the tenant and its environment from the session token
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	resp, err := rg.playProcessStageCleared(env, req, clientInfoFrom(c))
+	if err != nil {
+		return apierror.Write(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// playProcessStageCleared runs one stage-cleared step for a resolved session; it is shared by the REST and WebSocket transports
+func (rg *RouteGroup) playProcessStageCleared(env requestEnv, req ProcessStageClearedRequest, client clientInfo) (ProcessStageClearedResponse, error) {
+	if err := rg.beginStep(env, client, req.BetID, StepProcessStageCleared, &req.GameState); err != nil {
+		return ProcessStageClearedResponse{}, err
 	}
+	resp, err := rg.processStageCleared(env, req, client, newStepTrace(StepProcessStageCleared, req.GameState))
+	rg.endStep(env, req.BetID, resp.GameState, err)
+	return resp, err
+}
+
+// processStageCleared plays a stage-cleared step whose place in the round order has been checked
+func (rg *RouteGroup) processStageCleared(env requestEnv, req ProcessStageClearedRequest, client clientInfo, trace *stepTrace) (ProcessStageClearedResponse, error) {
+	rngClient, settingsClient := trace.providers(env.RNG, env.Settings)
+
+	// Identity comes from the session, never from the request body
+	req.ClientID, req.GameID, req.PlayerID = env.Session.ClientID, env.Session.GameID, env.Session.PlayerID
 
 	// Validate request
-	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID, req.GameState.Bet.Amount); err != nil {
+	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID); err != nil {
 		log.Printf("Request validation failed: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": err.Error(),
-		})
+		return ProcessStageClearedResponse{}, apierror.New(apierror.InvalidRequest, err.Error())
 	}
 
-	// Validate grid dimensions
-	if !ValidateGridDimensions(req.GameState.Grid, req.GameState.CurrentLevel) {
-		log.Printf("Invalid grid dimensions for level %d", req.GameState.CurrentLevel)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid grid dimensions",
-		})
+	// Load the operator's settings (RTP, bet ladder, win cap)
+	gameSettings, err := settingsClient.GetSettings(req.ClientID, req.GameID, req.PlayerID)
+	if err != nil {
+		log.Printf("Failed to get game settings: %v", err)
+		return ProcessStageClearedResponse{}, apierror.Wrap(apierror.SettingsUnavailable, err)
+	}
+	cur := env.Currency
+	bet, betMultiplier, err := validateBetAmount(req.GameState.Bet.Amount, req.GameState.Bet.Currency, cur, gameSettings, env.Session.MinBet, env.maxBet())
+	if err != nil {
+		log.Printf("Request validation failed: %v", err)
+		return ProcessStageClearedResponse{}, apierror.New(apierror.InvalidBet, err.Error())
 	}
 
-	// Create rand instance
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if req.GameState.MaxWinReached {
+		return ProcessStageClearedResponse{}, rejectIfRoundEnded(req.GameState)
+	}
+	req.GameState.Bet.Amount = cur.ToMajor(bet)
+	req.GameState.Bet.Currency = cur.Code
+	req.GameState.Bet.Multiplier = betMultiplier
+	winCap := roundWinCap(gameSettings, bet, cur, rg.maxWinMultiplierFor(env.Tenant))
+
+	// Reject game states that could not have come from a previous step
+	if err := ValidateGameState(req.GameState); err != nil {
+		log.Printf("Game state validation failed: %v", err)
+		return ProcessStageClearedResponse{}, err
+	}
+	// Refills continue from the reel position of the grid
+	reels, err := rg.reelsFor(env.Tenant, req.GameState, false)
+	if err != nil {
+		log.Printf("Game state validation failed: %v", err)
+		return ProcessStageClearedResponse{}, err
+	}
+
+	// Every random draw of the step is recorded for replay
+	r := trace.source
 
 	// Get stage-cleared symbols from the current grid
 	stageClearedSymbols := req.GameState.StageClearedSymbols
@@ -240,7 +383,7 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 		// Remove stage-cleared symbols from grid surgically
 		RemoveStageClearedSymbolsSurgical(req.GameState.Grid, stageClearedSymbols)
 		// Apply gravity surgically and get new positions
-		newPositions = ApplyGravitySurgical(req.GameState.Grid, stageClearedSymbols, req.GameState.CurrentLevel, r, req.GameState.GameMode == "freeSpins")
+		newPositions = ApplyGravitySurgical(req.GameState.Grid, stageClearedSymbols, req.GameState.CurrentLevel, req.GameState.GameMode, reels, r, req.GameState.GameMode == "freeSpins")
 		// Update stage progress
 		req.GameState.StageProgress += len(stageClearedSymbols)
 		log.Printf("Added %d stage-cleared symbols to progress, total: %d/15", len(stageClearedSymbols), req.GameState.StageProgress)
@@ -259,7 +402,12 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 			// Generate new grid for the new level
 			// Respect free spins mode - don't allow free game symbols during free spins
 			forbidFreeGame := req.GameState.GameMode == "freeSpins"
-			req.GameState.Grid = GenerateGrid(newLevel, r, forbidFreeGame)
+			reels, err = rg.reelsFor(env.Tenant, req.GameState, true)
+			if err != nil {
+				return ProcessStageClearedResponse{}, err
+			}
+			req.GameState.Grid = GenerateGrid(newLevel, req.GameState.GameMode, reels, r, forbidFreeGame)
+			req.GameState.Reels = reels.state()
 			levelAdvanced = true
 			log.Printf("Level advanced from %d to %d, excess progress: %d", oldLevel, newLevel, excessProgress)
 
@@ -269,32 +417,45 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 			req.GameState.StageClearedSymbols = stageClearedSymbolsAfterLevelUp
 
 			// Calculate winnings from the new grid
-			totalWinnings := 0.0
-			for i, connection := range connections {
-				payout := calculatePayout(connection.Symbol, connection.Count, req.GameState.CurrentLevel, req.GameState.Bet.Multiplier)
-				connections[i].Payout = payout
-				totalWinnings += payout
-			}
+			totalWinnings := rg.paytable().PayConnections(connections, req.GameState.CurrentLevel, req.GameState.GameMode, req.GameState.CascadeLevel, betMultiplier, cur)
 
 			// Check for and trigger free spins on the new grid
 			freeGameCount := CountFreeGameSymbols(req.GameState.Grid)
-			if req.GameState.GameMode == "base" && freeGameCount > 0 {
+			freeSpinsTriggered := req.GameState.GameMode == "base" && freeGameCount > 0
+			if freeSpinsTriggered {
 				req.GameState.GameMode = "freeSpins"
 				req.GameState.FreeSpins.Remaining = FreeSpinsAwarded
 				req.GameState.FreeSpins.TotalAwarded = FreeSpinsAwarded
 				req.GameState.FreeSpins.Multiplier = GetRandomFreeSpinMultiplier(r)
 				log.Printf("Free Spins triggered on new level with %.1fx multiplier", req.GameState.FreeSpins.Multiplier)
 				// Apply multiplier if free spins were just triggered
-				totalWinnings *= req.GameState.FreeSpins.Multiplier
+				totalWinnings = cur.Mul(totalWinnings, req.GameState.FreeSpins.Multiplier)
 			}
 
 			// Update game state for the response
-			req.GameState.TotalWin = round(totalWinnings)
+			totalWinnings, _ = CapRoundWin(cur.RoundMajor(req.GameState.RoundWin), totalWinnings, winCap)
+			maxWinReached := AddRoundWin(&req.GameState, totalWinnings, winCap, cur)
+			req.GameState.TotalWin = cur.ToMajor(totalWinnings)
 			req.GameState.LastConnections = connections
 			req.GameState.Cascading = len(connections) > 0
 			req.GameState.CascadeCount = 0 // Reset for new level
+			if maxWinReached {
+				EndRoundAtMaxWin(&req.GameState)
+			}
+			jackpot := rg.playJackpots(env, req.GameState, 0, connections, trace)
+			rg.scoreTournaments(env, req.GameState, bet, 0, stageClearedCount)
+			rg.recordStep("stageCleared", env, req.BetID, req.GameState, bet, 0, totalWinnings, jackpot, trace)
+
+			var events gameEvents
+			events.stageCleared(oldLevel, stageClearedSymbols)
+			events.levelAdvanced(newLevel)
+			events.clusterWins(newLevel, connections)
+			if freeSpinsTriggered {
+				events.freeSpinsTriggered(newLevel)
+			}
+			missions := rg.trackMissions(env, events)
 
-			return c.JSON(ProcessStageClearedResponse{
+			return ProcessStageClearedResponse{
 				Status:            "success",
 				Message:           "",
 				GameState:         req.GameState,
@@ -304,60 +465,48 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 				NewLevel:          newLevel,
 				Connections:       connections, // New connections from the new grid
 				TotalCost:         0,
-			})
+				MaxWinReached:     maxWinReached,
+				JackpotWins:       jackpot.wins,
+				Missions:          missions,
+			}, nil
 		}
 	}
 	// Clear the stage-cleared symbols from game state since they've been processed
 	req.GameState.StageClearedSymbols = []StageClearedSymbol{}
+	req.GameState.Reels = reels.state()
 
 	// NOW check for regular bird symbol connections in the new grid after gravity
 	connections := FindRegularConnections(req.GameState.Grid, req.GameState.CurrentLevel)
 
-	// Calculate total winnings
-	totalWinnings := 0.0
-	for i, connection := range connections {
-		payout := calculatePayout(connection.Symbol, connection.Count, req.GameState.CurrentLevel, req.GameState.Bet.Multiplier)
-		connections[i].Payout = payout // base payout, no multiplier
-		totalWinnings += payout
-	}
+	// Calculate total winnings in minor units; the refill climbs the cascade ladder
+	req.GameState.CascadeLevel++
+	totalWinnings := rg.paytable().PayConnections(connections, req.GameState.CurrentLevel, req.GameState.GameMode, req.GameState.CascadeLevel, betMultiplier, cur)
 	if req.GameState.GameMode == "freeSpins" {
-		totalWinnings *= req.GameState.FreeSpins.Multiplier
+		totalWinnings = cur.Mul(totalWinnings, req.GameState.FreeSpins.Multiplier)
 	}
-	totalWinnings = round(totalWinnings)
+	totalWinnings, _ = CapRoundWin(cur.RoundMajor(req.GameState.RoundWin), totalWinnings, winCap)
 
 	// Handle RNG for bird symbol connections (if any) with surgical loss approach
 	rngBypassed := false
 	if len(connections) > 0 {
-		rtp, err := settingsClient.GetRTP(req.ClientID, req.GameID, req.PlayerID)
-		if err != nil {
-			log.Printf("Failed to get RTP: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to retrieve game settings",
-			})
-		}
-
 		// Call RNG
-		payoutMultiplier := totalWinnings / req.GameState.Bet.Amount
-		ip := c.IP()
-		userAgent := c.Get("User-Agent")
+		payoutMultiplier := float64(totalWinnings) / float64(bet)
+		ip := client.IP
+		userAgent := client.UserAgent
 
 		log.Printf("✅IP: %v", ip)
 		log.Printf("✅User-Agent: %v", userAgent)
-		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, rtp, payoutMultiplier, req.GameState.Bet.Amount, ip, userAgent)
+		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, gameSettings.RTP, payoutMultiplier, cur.ToMajor(bet), cur.Code, ip, userAgent)
 		if err != nil {
 			log.Printf("Failed to call RNG API: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to determine outcome",
-			})
+			return ProcessStageClearedResponse{}, apierror.Wrap(apierror.RNGUnavailable, err)
 		}
 
 		// SURGICAL LOSS: Adjust outcome based on RNG while preserving grid structure
 		if rngResp.PrefOutcome == "loss" {
 			log.Printf("RNG determined a loss outcome for stage-cleared processing")
 			// Try surgical loss approach first (only new positions)
-			success := ApplySurgicalLoss(&req.GameState, originalGrid, stageClearedSymbols, req.GameState.CurrentLevel, r, newPositions)
+			success := ApplySurgicalLoss(&req.GameState, originalGrid, stageClearedSymbols, req.GameState.CurrentLevel, reels, r, newPositions)
 			if !success {
 				// If surgical loss is impossible, bypass RNG and allow the win
 				log.Printf("⚠️  RNG BYPASS: Surgical loss impossible after stage-cleared processing - preserving natural outcome")
@@ -376,7 +525,8 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 	}
 
 	// Update game state with connection results
-	req.GameState.TotalWin = totalWinnings
+	maxWinReached := AddRoundWin(&req.GameState, totalWinnings, winCap, cur)
+	req.GameState.TotalWin = cur.ToMajor(totalWinnings)
 	req.GameState.LastConnections = connections
 	req.GameState.Cascading = len(connections) > 0
 
@@ -387,6 +537,9 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 	} else {
 		req.GameState.CascadeCount = 0
 	}
+	if maxWinReached {
+		EndRoundAtMaxWin(&req.GameState)
+	}
 
 	logMessage := fmt.Sprintf("ProcessStageCleared completed: stageClearedCount=%d, levelAdvanced=%v, oldLevel=%d, newLevel=%d, progress=%d, cascading=%v",
 		stageClearedCount, levelAdvanced, oldLevel, newLevel, req.GameState.StageProgress, req.GameState.Cascading)
@@ -395,9 +548,17 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 		logMessage += " [RNG BYPASSED - Surgical loss impossible]"
 	}
 
-	log.Printf(logMessage)
+	log.Print(logMessage)
+	jackpot := rg.playJackpots(env, req.GameState, 0, connections, trace)
+	rg.scoreTournaments(env, req.GameState, bet, 0, stageClearedCount)
+	rg.recordStep("stageCleared", env, req.BetID, req.GameState, bet, 0, totalWinnings, jackpot, trace)
 
-	return c.JSON(ProcessStageClearedResponse{
+	var events gameEvents
+	events.stageCleared(req.GameState.CurrentLevel, stageClearedSymbols)
+	events.clusterWins(req.GameState.CurrentLevel, connections)
+	missions := rg.trackMissions(env, events)
+
+	return ProcessStageClearedResponse{
 		Status:            "success",
 		Message:           "",
 		GameState:         req.GameState,
@@ -407,7 +568,10 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 		NewLevel:          newLevel,
 		Connections:       connections,
 		TotalCost:         0,
-	})
+		MaxWinReached:     maxWinReached,
+		JackpotWins:       jackpot.wins,
+		Missions:          missions,
+	}, nil
 }
 
 // CascadeHandler handles the /cascade/birdsparty endpoint
@@ -415,40 +579,89 @@ func (rg *RouteGroup) ProcessStageClearedHandler(c *fiber.Ctx) error {
 // It does NOT process stage-cleared symbols - client must call process-stage-cleared endpoint
 // FIXED: Now uses surgical cascade processing to preserve grid structure
 func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
-	rngClient, settingsClient := rg.getClientsForRequest(c)
-
 	var req CascadeRequest
 	if err := c.BodyParser(&req); err != nil {
 		log.Printf("Failed to parse request body: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid request body",
-		})
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
 	}
 
+	// Resolve the tenant and its environment from the session token
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	resp, err := rg.playCascade(env, req, clientInfoFrom(c))
+	if err != nil {
+		return apierror.Write(c, err)
+	}
+	return c.JSON(resp)
+}
+
+// playCascade runs one cascade step for a resolved session; it is shared by the REST and WebSocket transports
+func (rg *RouteGroup) playCascade(env requestEnv, req CascadeRequest, client clientInfo) (CascadeResponse, error) {
+	if err := rg.beginStep(env, client, req.BetID, StepCascade, &req.GameState); err != nil {
+		return CascadeResponse{}, err
+	}
+	resp, err := rg.cascade(env, req, client, newStepTrace(StepCascade, req.GameState))
+	rg.endStep(env, req.BetID, resp.GameState, err)
+	return resp, err
+}
+
+// cascade plays a cascade whose place in the round order has been checked
+func (rg *RouteGroup) cascade(env requestEnv, req CascadeRequest, client clientInfo, trace *stepTrace) (CascadeResponse, error) {
+	rngClient, settingsClient := trace.providers(env.RNG, env.Settings)
+
+	// Identity comes from the session, never from the request body
+	req.ClientID, req.GameID, req.PlayerID = env.Session.ClientID, env.Session.GameID, env.Session.PlayerID
+
 	// Validate request
-	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID, req.GameState.Bet.Amount); err != nil {
+	if err := validateRequest(req.ClientID, req.GameID, req.PlayerID, req.BetID); err != nil {
+		log.Printf("Request validation failed: %v", err)
+		return CascadeResponse{}, apierror.New(apierror.InvalidRequest, err.Error())
+	}
+
+	// Load the operator's settings (RTP, bet ladder, win cap)
+	gameSettings, err := settingsClient.GetSettings(req.ClientID, req.GameID, req.PlayerID)
+	if err != nil {
+		log.Printf("Failed to get game settings: %v", err)
+		return CascadeResponse{}, apierror.Wrap(apierror.SettingsUnavailable, err)
+	}
+	cur := env.Currency
+	bet, betMultiplier, err := validateBetAmount(req.GameState.Bet.Amount, req.GameState.Bet.Currency, cur, gameSettings, env.Session.MinBet, env.maxBet())
+	if err != nil {
 		log.Printf("Request validation failed: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": err.Error(),
-		})
+		return CascadeResponse{}, apierror.New(apierror.InvalidBet, err.Error())
 	}
 
-	// Validate grid dimensions
-	if !ValidateGridDimensions(req.GameState.Grid, req.GameState.CurrentLevel) {
-		log.Printf("Invalid grid dimensions for level %d", req.GameState.CurrentLevel)
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Invalid grid dimensions",
-		})
+	if req.GameState.MaxWinReached {
+		return CascadeResponse{}, rejectIfRoundEnded(req.GameState)
 	}
+	req.GameState.Bet.Amount = cur.ToMajor(bet)
+	req.GameState.Bet.Currency = cur.Code
+	req.GameState.Bet.Multiplier = betMultiplier
+	winCap := roundWinCap(gameSettings, bet, cur, rg.maxWinMultiplierFor(env.Tenant))
 
-	// Increment cascade count
+	// Reject game states that could not have come from a previous step
+	if err := ValidateGameState(req.GameState); err != nil {
+		log.Printf("Game state validation failed: %v", err)
+		return CascadeResponse{}, err
+	}
+	if !req.GameState.Cascading {
+		return CascadeResponse{}, apierror.New(apierror.InvalidGameState, "no cascade pending, cascading is false")
+	}
+	// Refills continue from the reel position of the grid
+	reels, err := rg.reelsFor(env.Tenant, req.GameState, false)
+	if err != nil {
+		log.Printf("Game state validation failed: %v", err)
+		return CascadeResponse{}, err
+	}
+
+	// Increment cascade count and climb the cascade multiplier ladder
 	req.GameState.CascadeCount++
+	req.GameState.CascadeLevel++
 
-	// Create rand instance
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// Every random draw of the step is recorded for replay
+	r := trace.source
 
 	// PRESERVE ORIGINAL GRID before processing for surgical loss capability
 	originalGrid := make([][]string, len(req.GameState.Grid))
@@ -458,7 +671,7 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 	}
 
 	var connections []Connection
-	var totalWinnings float64
+	var totalWinnings money.Amount
 	var affectedPositions []Position
 	var newPositions []Position
 
@@ -466,7 +679,7 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 	if req.GameState.CascadeCount >= 1 && len(req.GameState.LastConnections) > 0 {
 		// SURGICAL: Remove previous connections and apply gravity surgically
 		affectedPositions = RemoveConnectionsSurgical(req.GameState.Grid, req.GameState.LastConnections)
-		newPositions = ApplyGravitySurgicalForCascade(req.GameState.Grid, affectedPositions, req.GameState.CurrentLevel, r, req.GameState.GameMode == "freeSpins")
+		newPositions = ApplyGravitySurgicalForCascade(req.GameState.Grid, affectedPositions, req.GameState.CurrentLevel, req.GameState.GameMode, reels, r, req.GameState.GameMode == "freeSpins")
 	} else {
 		// First cascade call - find existing connections
 		connections = FindRegularConnections(req.GameState.Grid, req.GameState.CurrentLevel)
@@ -475,7 +688,7 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 			for _, connection := range connections {
 				affectedPositions = append(affectedPositions, connection.Positions...)
 			}
-			newPositions = ApplyGravitySurgicalForCascade(req.GameState.Grid, affectedPositions, req.GameState.CurrentLevel, r, req.GameState.GameMode == "freeSpins")
+			newPositions = ApplyGravitySurgicalForCascade(req.GameState.Grid, affectedPositions, req.GameState.CurrentLevel, req.GameState.GameMode, reels, r, req.GameState.GameMode == "freeSpins")
 		}
 	}
 
@@ -484,44 +697,27 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 		connections = FindRegularConnections(req.GameState.Grid, req.GameState.CurrentLevel)
 	}
 
-	// Calculate total winnings
-	totalWinnings = 0.0
-	for i, connection := range connections {
-		payout := calculatePayout(connection.Symbol, connection.Count, req.GameState.CurrentLevel, req.GameState.Bet.Multiplier)
-		connections[i].Payout = payout // base payout, no multiplier
-		totalWinnings += payout
-	}
+	// Calculate total winnings in minor units
+	totalWinnings = rg.paytable().PayConnections(connections, req.GameState.CurrentLevel, req.GameState.GameMode, req.GameState.CascadeLevel, betMultiplier, cur)
 	if req.GameState.GameMode == "freeSpins" {
-		totalWinnings *= req.GameState.FreeSpins.Multiplier
+		totalWinnings = cur.Mul(totalWinnings, req.GameState.FreeSpins.Multiplier)
 	}
-	totalWinnings = round(totalWinnings)
+	totalWinnings, _ = CapRoundWin(cur.RoundMajor(req.GameState.RoundWin), totalWinnings, winCap)
 
 	// Handle RNG for bird symbol connections with surgical loss approach
 	rngBypassed := false
 	if len(connections) > 0 {
-		rtp, err := settingsClient.GetRTP(req.ClientID, req.GameID, req.PlayerID)
-		if err != nil {
-			log.Printf("Failed to get RTP: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to retrieve game settings",
-			})
-		}
-
 		// Call RNG
-		payoutMultiplier := totalWinnings / req.GameState.Bet.Amount
-		ip := c.IP()
-		userAgent := c.Get("User-Agent")
+		payoutMultiplier := float64(totalWinnings) / float64(bet)
+		ip := client.IP
+		userAgent := client.UserAgent
 
 		log.Printf("✅IP: %v", ip)
 		log.Printf("✅User-Agent: %v", userAgent)
-		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, rtp, payoutMultiplier, req.GameState.Bet.Amount, ip, userAgent)
+		rngResp, err := rngClient.GetOutcome(req.ClientID, req.GameID, req.PlayerID, req.BetID, gameSettings.RTP, payoutMultiplier, cur.ToMajor(bet), cur.Code, ip, userAgent)
 		if err != nil {
 			log.Printf("Failed to call RNG API: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Failed to determine outcome",
-			})
+			return CascadeResponse{}, apierror.Wrap(apierror.RNGUnavailable, err)
 		}
 
 		// SURGICAL LOSS: Adjust outcome based on RNG while preserving grid structure
@@ -529,7 +725,7 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 			log.Printf("RNG determined a loss outcome for cascade")
 
 			// Try surgical loss approach first (only new positions)
-			success := ApplySurgicalLossForCascade(&req.GameState, originalGrid, newPositions, req.GameState.CurrentLevel, r)
+			success := ApplySurgicalLossForCascade(&req.GameState, originalGrid, newPositions, req.GameState.CurrentLevel, reels, r)
 
 			if !success {
 				// If surgical loss is impossible, bypass RNG and allow the win
@@ -557,9 +753,11 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 	req.GameState.StageClearedSymbols = stageClearedSymbols
 
 	// Check for free game symbols if connections were removed by RNG or no connections exist
+	freeSpinsTriggered := false
 	if len(connections) == 0 && !rngBypassed {
 		freeGameCount := CountFreeGameSymbols(req.GameState.Grid)
-		if req.GameState.GameMode == "base" && freeGameCount > 0 {
+		freeSpinsTriggered = req.GameState.GameMode == "base" && freeGameCount > 0
+		if freeSpinsTriggered {
 			req.GameState.GameMode = "freeSpins"
 			req.GameState.FreeSpins.Remaining = FreeSpinsAwarded
 			req.GameState.FreeSpins.TotalAwarded = FreeSpinsAwarded
@@ -569,21 +767,42 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 	}
 
 	// Update game state
-	req.GameState.TotalWin = totalWinnings
+	maxWinReached := AddRoundWin(&req.GameState, totalWinnings, winCap, cur)
+	req.GameState.Reels = reels.state()
+	req.GameState.TotalWin = cur.ToMajor(totalWinnings)
 	req.GameState.LastConnections = connections
 	req.GameState.Cascading = len(connections) > 0
+	// The chain of cascades ends with the last one; as after stage-cleared processing, no pending cascade counts 0
+	if !req.GameState.Cascading {
+		req.GameState.CascadeCount = 0
+	}
+	if maxWinReached {
+		EndRoundAtMaxWin(&req.GameState)
+		stageClearedSymbols = req.GameState.StageClearedSymbols
+		hasStageCleared = false
+	}
 
-	logMessage := fmt.Sprintf("Cascade completed: level=%d, gridSize=%dx%d, totalWin=%.2f, cascading=%v, cascadeCount=%d, stageClearedDetected=%v",
+	logMessage := fmt.Sprintf("Cascade completed: level=%d, gridSize=%dx%d, totalWin=%s, cascading=%v, cascadeCount=%d, cascadeLevel=%d, stageClearedDetected=%v",
 		req.GameState.CurrentLevel, req.GameState.GridSize, req.GameState.GridSize,
-		totalWinnings, req.GameState.Cascading, req.GameState.CascadeCount, hasStageCleared)
+		cur.Format(totalWinnings), req.GameState.Cascading, req.GameState.CascadeCount, req.GameState.CascadeLevel, hasStageCleared)
 
 	if rngBypassed {
 		logMessage += " [RNG BYPASSED - Surgical loss impossible]"
 	}
 
-	log.Printf(logMessage)
+	log.Print(logMessage)
+	jackpot := rg.playJackpots(env, req.GameState, 0, connections, trace)
+	rg.scoreTournaments(env, req.GameState, bet, 0, 0)
+	rg.recordStep("cascade", env, req.BetID, req.GameState, bet, 0, totalWinnings, jackpot, trace)
+
+	var events gameEvents
+	events.clusterWins(req.GameState.CurrentLevel, connections)
+	if freeSpinsTriggered {
+		events.freeSpinsTriggered(req.GameState.CurrentLevel)
+	}
+	missions := rg.trackMissions(env, events)
 
-	return c.JSON(CascadeResponse{
+	return CascadeResponse{
 		Status:              "success",
 		Message:             "",
 		GameState:           req.GameState,
@@ -591,11 +810,14 @@ func (rg *RouteGroup) CascadeHandler(c *fiber.Ctx) error {
 		StageClearedSymbols: stageClearedSymbols, // Include detected stage-cleared symbols
 		HasStageCleared:     hasStageCleared,     // Flag to indicate stage-cleared symbols found
 		TotalCost:           0,
-	})
+		MaxWinReached:       maxWinReached,
+		JackpotWins:         jackpot.wins,
+		Missions:            missions,
+	}, nil
 }
 
 // validateRequest validates the request fields
-func validateRequest(clientID, gameID, playerID, betID string, betAmount float64) error {
+func validateRequest(clientID, gameID, playerID, betID string) error {
 	if clientID == "" {
 		return fmt.Errorf("client_id is required")
 	}
@@ -608,19 +830,122 @@ func validateRequest(clientID, gameID, playerID, betID string, betAmount float64
 	if betID == "" {
 		return fmt.Errorf("bet_id is required")
 	}
-	if !isValidBetAmount(betAmount) {
-		return fmt.Errorf("invalid bet amount, allowed values are 0.1, 0.2, 0.3, 0.5, 1.0")
-	}
 	return nil
 }
 
-// isValidBetAmount checks if the bet amount is valid
-func isValidBetAmount(amount float64) bool {
-	validAmounts := []float64{0.1, 0.2, 0.3, 0.5, 1.0}
-	for _, valid := range validAmounts {
-		if amount == valid {
-			return true
+// validateBetAmount checks the bet against the operator's ladder, falling back to the currency's
+// default ladder, and against the session's bet limits (0 means no limit).
+// It returns the bet in minor units of cur and its bet multiplier.
+func validateBetAmount(amount float64, currency string, cur money.Currency, gameSettings settings.Settings, minBet, maxBet float64) (money.Amount, int, error) {
+	if currency != "" && !strings.EqualFold(currency, cur.Code) {
+		return 0, 0, fmt.Errorf("invalid bet currency %s, session currency is %s", currency, cur.Code)
+	}
+	bet, err := cur.FromMajor(amount)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid bet amount: %v", err)
+	}
+	if maxBet > 0 && bet > cur.RoundMajor(maxBet) {
+		return 0, 0, fmt.Errorf("invalid bet amount, maximum bet is %s", cur.Format(cur.RoundMajor(maxBet)))
+	}
+	if minBet > 0 && bet < cur.RoundMajor(minBet) {
+		return 0, 0, fmt.Errorf("invalid bet amount, minimum bet is %s", cur.Format(cur.RoundMajor(minBet)))
+	}
+
+	ladder := cur.BetLadder
+	if len(gameSettings.BetAmounts) > 0 {
+		ladder = make([]money.Amount, len(gameSettings.BetAmounts))
+		for i, a := range gameSettings.BetAmounts {
+			ladder[i] = cur.RoundMajor(a)
+		}
+	}
+	if !slices.Contains(ladder, bet) {
+		allowed := make([]string, len(ladder))
+		for i, a := range ladder {
+			allowed[i] = cur.Format(a)
+		}
+		return 0, 0, fmt.Errorf("invalid bet amount, allowed values are %s", strings.Join(allowed, ", "))
+	}
+
+	betMultiplier, err := BetMultiplier(bet, cur)
+	if err != nil {
+		return 0, 0, err
+	}
+	return bet, betMultiplier, nil
+}
+
+// roundWinCap returns the maximum total win for a round at the given bet; 0 means uncapped.
+// The operator's bet multiple takes precedence over the server default, and an absolute
+// operator maximum further lowers the cap when it is smaller.
+func roundWinCap(gameSettings settings.Settings, bet money.Amount, cur money.Currency, defaultMultiplier float64) money.Amount {
+	multiplier := gameSettings.MaxWinMultiplier
+	if multiplier <= 0 {
+		multiplier = defaultMultiplier
+	}
+
+	var winCap money.Amount
+	if multiplier > 0 {
+		winCap = cur.Mul(bet, multiplier)
+	}
+	if gameSettings.MaxWin > 0 {
+		maxWin := cur.RoundMajor(gameSettings.MaxWin)
+		if winCap == 0 || maxWin < winCap {
+			winCap = maxWin
 		}
 	}
-	return false
+	return winCap
+}
+
+// recordStep records a completed game step, with its amounts in minor units of the session currency,
+// in the audit trail, with what it consumed for replay and its jackpot contribution and wins, in
+// the responsible-gaming tracker and, during a free round, in the winnings of its grant
+func (rg *RouteGroup) recordStep(event string, env requestEnv, betID string, gameState GameState, bet, cost, win money.Amount, jackpot jackpotStep, trace *stepTrace) {
+	if rg.Responsible != nil {
+		rg.Responsible.Record(playerOf(env), sessionOf(env), cost, win+jackpot.won, env.Currency)
+	}
+	var freeRound string
+	if rg.FreeRounds != nil {
+		freeRound = rg.FreeRounds.credit(env.Session.ClientID, env.Session.PlayerID, win+jackpot.won)
+	}
+	var jackpots []string
+	for _, w := range jackpot.wins {
+		jackpots = append(jackpots, w.Pool)
+	}
+	rg.Audit.Log(audit.Record{
+		Event:               event,
+		SessionID:           env.Session.SessionID,
+		ClientID:            env.Session.ClientID,
+		PlayerID:            env.Session.PlayerID,
+		GameID:              env.Session.GameID,
+		BetID:               betID,
+		Currency:            env.Currency.Code,
+		Bet:                 bet,
+		Cost:                cost,
+		Win:                 win,
+		RoundWin:            env.Currency.RoundMajor(gameState.RoundWin),
+		Level:               int(gameState.CurrentLevel),
+		GameMode:            gameState.GameMode,
+		MaxWinReached:       gameState.MaxWinReached,
+		JackpotContribution: jackpot.contribution,
+		JackpotWin:          jackpot.won,
+		Jackpots:            jackpots,
+		FreeRound:           freeRound,
+		Replay:              trace.finish(betID, env, rg.maxWinMultiplierFor(env.Tenant), gameState),
+	})
+}
+
+// rejectIfRoundEnded refuses follow-up calls for a round that was terminated by the max win cap
+func rejectIfRoundEnded(gameState GameState) error {
+	log.Printf("Rejected follow-up call: round already ended at max win %.2f", gameState.RoundWin)
+	return apierror.New(apierror.RoundEnded, "")
+}
+
+// clientInfo is the caller's network identity forwarded to the RNG service
+type clientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// clientInfoFrom reads the caller's network identity from a request
+func clientInfoFrom(c *fiber.Ctx) clientInfo {
+	return clientInfo{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
 }
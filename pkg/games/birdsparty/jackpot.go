@@ -0,0 +1,243 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JackpotPool configures one progressive jackpot pool, such as mini, major or grand
+type JackpotPool struct {
+	Name string `json:"name"`
+	// ContributionPercent is the share of every staked bet added to the pool
+	ContributionPercent float64 `json:"contributionPercent"`
+	// Seeds are the values the pool starts from and resets to after a win, by currency code in
+	// major units; a currency without a seed starts from 0
+	Seeds   map[string]float64 `json:"seeds"`
+	Trigger JackpotTrigger     `json:"trigger"`
+}
+
+// JackpotTrigger holds the conditions that win a pool; any one of them is enough
+type JackpotTrigger struct {
+	Cluster *ClusterTrigger `json:"cluster,omitempty"`
+	// RandomChance is the chance that a staked spin at bet multiplier 1 wins the pool; it grows
+	// in proportion to the bet multiplier
+	RandomChance float64 `json:"randomChance,omitempty"`
+}
+
+// ClusterTrigger is won by a paying connection of Symbol with at least MinCount symbols on Level;
+// a Level of 0 matches every level
+type ClusterTrigger struct {
+	Level    Level  `json:"level,omitempty"`
+	Symbol   Symbol `json:"symbol"`
+	MinCount int    `json:"minCount"`
+}
+
+// matches reports whether one of a step's paying connections wins the pool
+func (t *ClusterTrigger) matches(level Level, connections []Connection) bool {
+	if t == nil || (t.Level != 0 && t.Level != level) {
+		return false
+	}
+	for _, connection := range connections {
+		if connection.Symbol == t.Symbol && connection.Count >= t.MinCount {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadJackpots reads and validates jackpot pools from a JSON file:
+//
+//	[{"name": "grand", "contributionPercent": 0.5, "seeds": {"USD": 1000, "EUR": 1000},
+//	  "trigger": {"cluster": {"level": 3, "symbol": "red_owl", "minCount": 36}, "randomChance": 0.000001}}, ...]
+func LoadJackpots(path string) ([]JackpotPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading jackpots: %w", err)
+	}
+	var pools []JackpotPool
+	if err := json.Unmarshal(data, &pools); err != nil {
+		return nil, fmt.Errorf("parsing jackpots %s: %w", path, err)
+	}
+	if err := ValidateJackpots(pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// ValidateJackpots checks that pools have unique names, contributions that add up to at most the
+// whole bet, non-negative seeds in supported currencies and at least one valid trigger
+func ValidateJackpots(pools []JackpotPool) error {
+	names := make(map[string]bool)
+	totalPercent := 0.0
+	for i, pool := range pools {
+		switch {
+		case pool.Name == "":
+			return fmt.Errorf("jackpot %d has no name", i)
+		case names[pool.Name]:
+			return fmt.Errorf("jackpot %q is defined twice", pool.Name)
+		case pool.ContributionPercent < 0:
+			return fmt.Errorf("jackpot %q contributionPercent %v is negative", pool.Name, pool.ContributionPercent)
+		}
+		names[pool.Name] = true
+		totalPercent += pool.ContributionPercent
+
+		for code, seed := range pool.Seeds {
+			if _, ok := money.Lookup(code); !ok {
+				return fmt.Errorf("jackpot %q has a seed in unsupported currency %q", pool.Name, code)
+			}
+			if seed < 0 {
+				return fmt.Errorf("jackpot %q %s seed %v is negative", pool.Name, code, seed)
+			}
+		}
+
+		trigger := pool.Trigger
+		if trigger.Cluster == nil && trigger.RandomChance == 0 {
+			return fmt.Errorf("jackpot %q has no trigger", pool.Name)
+		}
+		if trigger.RandomChance < 0 || trigger.RandomChance > 1 {
+			return fmt.Errorf("jackpot %q randomChance %v is outside 0-1", pool.Name, trigger.RandomChance)
+		}
+		if cluster := trigger.Cluster; cluster != nil {
+			if cluster.Level != 0 {
+				if err := cluster.Level.ValidateLevel(); err != nil {
+					return fmt.Errorf("jackpot %q cluster trigger: %w", pool.Name, err)
+				}
+			}
+			if !IsRegularBirdSymbol(cluster.Symbol) {
+				return fmt.Errorf("jackpot %q cluster trigger: %q is not a regular bird symbol", pool.Name, cluster.Symbol)
+			}
+			if cluster.MinCount < Level1.GetMinConnection() {
+				return fmt.Errorf("jackpot %q cluster trigger minCount %d is below the smallest paying cluster", pool.Name, cluster.MinCount)
+			}
+		}
+	}
+	if totalPercent > 100 {
+		return fmt.Errorf("jackpot contributions add up to %v%% of the bet", totalPercent)
+	}
+	return nil
+}
+
+// Jackpots holds the value of every jackpot pool by operator and currency. Contributing to,
+// winning and resetting pools happens under one lock, so a pool is won at most once and no
+// contribution is lost to a reset. State is held in memory, so it is per instance and does not
+// survive a restart.
+type Jackpots struct {
+	mu     sync.Mutex
+	pools  []JackpotPool
+	values map[string]float64 // Minor units by operator, currency and pool; fractions of a minor unit accumulate
+}
+
+// NewJackpots creates jackpots for the given pools, each starting from its seed
+func NewJackpots(pools []JackpotPool) *Jackpots {
+	return &Jackpots{
+		pools:  pools,
+		values: make(map[string]float64),
+	}
+}
+
+func jackpotKey(clientID string, cur money.Currency, pool string) string {
+	return clientID + "|" + cur.Code + "|" + pool
+}
+
+// seed returns the value the pool starts from in minor units of cur
+func (p JackpotPool) seed(cur money.Currency) float64 {
+	return float64(cur.RoundMajor(p.Seeds[cur.Code]))
+}
+
+// value returns the pool's value for the operator and currency, seeding it on first use; the caller holds j.mu
+func (j *Jackpots) value(clientID string, cur money.Currency, pool JackpotPool) float64 {
+	key := jackpotKey(clientID, cur, pool.Name)
+	v, ok := j.values[key]
+	if !ok {
+		v = pool.seed(cur)
+		j.values[key] = v
+	}
+	return v
+}
+
+// Values returns the current value of every pool for the operator and currency
+func (j *Jackpots) Values(clientID string, cur money.Currency) []JackpotValue {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	values := make([]JackpotValue, 0, len(j.pools))
+	for _, pool := range j.pools {
+		amount := money.Amount(math.Floor(j.value(clientID, cur, pool)))
+		values = append(values, JackpotValue{Pool: pool.Name, Amount: cur.ToMajor(amount)})
+	}
+	return values
+}
+
+// jackpotStep is what one game step contributed to and won from the jackpot pools
+type jackpotStep struct {
+	contribution float64      // Minor units, may be fractional
+	won          money.Amount // Total of wins
+	wins         []JackpotWin
+}
+
+// play adds the contributions of a step's stake to every pool, then awards each pool the step
+// triggers and resets it to its seed. A pool pays its whole minor units; the fraction left over
+// stays in the pool. Random triggers are only drawn for staked steps, from the step's source r, so
+// they are recorded with the step's other draws.
+func (j *Jackpots) play(clientID string, cur money.Currency, stake money.Amount, betMultiplier int, level Level, connections []Connection, r Source) jackpotStep {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var step jackpotStep
+	for _, pool := range j.pools {
+		key := jackpotKey(clientID, cur, pool.Name)
+		contribution := float64(stake) * pool.ContributionPercent / 100
+		v := j.value(clientID, cur, pool) + contribution
+		j.values[key] = v
+		step.contribution += contribution
+
+		won := pool.Trigger.Cluster.matches(level, connections)
+		if !won && stake > 0 && pool.Trigger.RandomChance > 0 {
+			won = r.Float64() < math.Min(1, pool.Trigger.RandomChance*float64(betMultiplier))
+		}
+		if !won {
+			continue
+		}
+
+		amount := money.Amount(math.Floor(v))
+		j.values[key] = pool.seed(cur) + v - float64(amount)
+		step.won += amount
+		step.wins = append(step.wins, JackpotWin{Pool: pool.Name, Amount: cur.ToMajor(amount)})
+	}
+	return step
+}
+
+// playJackpots contributes a step's stake to the jackpot pools and awards the pools its paying
+// connections or a random draw from the step's trace triggers; it is a no-op when jackpots are disabled
+func (rg *RouteGroup) playJackpots(env requestEnv, gameState GameState, stake money.Amount, connections []Connection, trace *stepTrace) jackpotStep {
+	if rg.Jackpots == nil {
+		return jackpotStep{}
+	}
+	step := rg.Jackpots.play(env.Session.ClientID, env.Currency, stake, gameState.Bet.Multiplier, gameState.CurrentLevel, connections, trace.source)
+	for _, win := range step.wins {
+		log.Printf("Player %s won the %s jackpot: %.2f %s", env.Session.PlayerID, win.Pool, win.Amount, env.Currency.Code)
+	}
+	return step
+}
+
+// JackpotsHandler handles the /jackpots/birdsparty endpoint
+// Returns the current value of every jackpot pool in the session's currency for display
+func (rg *RouteGroup) JackpotsHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+
+	resp := JackpotsResponse{Status: "success", Message: "", Currency: env.Currency.Code, Jackpots: []JackpotValue{}}
+	if rg.Jackpots != nil {
+		resp.Jackpots = rg.Jackpots.Values(env.Session.ClientID, env.Currency)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,134 @@
+package birdsparty
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/auth"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/tenant"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LaunchHandler handles the /session/launch endpoint
+// Called by the operator (HMAC-signed) to mint a session token bound to a player,
+// currency, bet limits and the tenant's environment. The game client then uses
+// only that token on the game endpoints.
+func (rg *RouteGroup) LaunchHandler(c *fiber.Ctx) error {
+	var req LaunchRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse launch request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	if req.GameID == "" {
+		req.GameID = GameID
+	}
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	if err := validateLaunchRequest(req); err != nil {
+		log.Printf("Launch validation failed: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, err.Error()))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+
+	// Self-excluded players cannot start a session
+	if rg.Responsible != nil {
+		if err := rg.Responsible.CheckExclusion(responsible.Player{ClientID: t.ClientID, PlayerID: req.PlayerID}); err != nil {
+			log.Printf("Launch refused for player %s: %v", req.PlayerID, err)
+			return apierror.Write(c, err)
+		}
+	}
+
+	// The tenant's maximum bet is a ceiling the operator cannot raise per session
+	maxBet := req.MaxBet
+	if t.Limits.MaxBet > 0 && (maxBet == 0 || maxBet > t.Limits.MaxBet) {
+		maxBet = t.Limits.MaxBet
+	}
+	if maxBet > 0 && req.MinBet > maxBet {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "min_bet is above the maximum bet"))
+	}
+
+	token, claims, err := rg.Sessions.Issue(session.Claims{
+		ClientID:    t.ClientID,
+		PlayerID:    req.PlayerID,
+		GameID:      req.GameID,
+		Currency:    req.Currency,
+		MinBet:      req.MinBet,
+		MaxBet:      maxBet,
+		Environment: t.Environment,
+	})
+	if err != nil {
+		log.Printf("Failed to issue session token: %v", err)
+		return apierror.Write(c, apierror.New(apierror.Internal, "Failed to create session"))
+	}
+
+	launchURL := ""
+	if rg.LaunchURL != "" {
+		launchURL = rg.LaunchURL + "?token=" + url.QueryEscape(token)
+	}
+
+	log.Printf("Session launched: session=%s client=%s player=%s currency=%s env=%s",
+		claims.SessionID, claims.ClientID, claims.PlayerID, claims.Currency, claims.Environment)
+
+	// A round left unfinished by an earlier session is resumed or completed now
+	unfinished, recovered := rg.recoverOnLaunch(t.ClientID, req.PlayerID)
+
+	return c.JSON(LaunchResponse{
+		Status:          "success",
+		Message:         "",
+		Token:           token,
+		SessionID:       claims.SessionID,
+		ExpiresAt:       claims.ExpiresAt,
+		LaunchURL:       launchURL,
+		UnfinishedRound: unfinished,
+		RecoveredRounds: recovered,
+	})
+}
+
+// authenticateOperator checks that an operator request was signed by clientID itself and carries its API key
+func (rg *RouteGroup) authenticateOperator(c *fiber.Ctx, clientID string) (tenant.Tenant, error) {
+	// The operator that signed the request may only act for itself
+	if signedBy := auth.ClientID(c); signedBy != "" && signedBy != clientID {
+		return tenant.Tenant{}, fmt.Errorf("%w: request signed by %q", errClientMismatch, signedBy)
+	}
+	return rg.Tenants.Authenticate(clientID, c.Get(APIKeyHeader))
+}
+
+// validateLaunchRequest validates the launch request fields
+func validateLaunchRequest(req LaunchRequest) error {
+	if req.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if req.PlayerID == "" {
+		return fmt.Errorf("player_id is required")
+	}
+	if req.GameID != GameID {
+		return fmt.Errorf("unknown game_id %q", req.GameID)
+	}
+	if req.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	cur, ok := money.Lookup(req.Currency)
+	if !ok {
+		return fmt.Errorf("unsupported currency %q", req.Currency)
+	}
+	if req.MinBet < 0 || req.MaxBet < 0 {
+		return fmt.Errorf("bet limits must not be negative")
+	}
+	if _, err := cur.FromMajor(req.MinBet); err != nil {
+		return fmt.Errorf("invalid min_bet: %v", err)
+	}
+	if _, err := cur.FromMajor(req.MaxBet); err != nil {
+		return fmt.Errorf("invalid max_bet: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Mission is a goal players reach by playing, counted from the game events of their steps.
+// Symbol, MinCount and Level narrow the events that count; their zero values match every event.
+type Mission struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Event    GameEventType `json:"event"`
+	Symbol   Symbol        `json:"symbol,omitempty"`
+	MinCount int           `json:"minCount,omitempty"` // Smallest cluster of a clusterWin
+	Level    Level         `json:"level,omitempty"`
+	// Target is the progress that completes the mission; a stageCleared event adds the number of
+	// symbols collected, every other event adds 1
+	Target int `json:"target"`
+}
+
+// progressFrom returns the progress an event makes on the mission
+func (m Mission) progressFrom(e GameEvent) int {
+	if e.Type != m.Event || (m.Symbol != "" && e.Symbol != m.Symbol) || (m.Level != 0 && e.Level != m.Level) || e.Count < m.MinCount {
+		return 0
+	}
+	if e.Type == EventStageCleared {
+		return e.Count
+	}
+	return 1
+}
+
+// LoadMissions reads and validates missions from a JSON file:
+//
+//	[{"id": "honey-30", "name": "Collect 30 honey pots", "event": "stageCleared", "symbol": "honey_pot", "target": 30},
+//	 {"id": "blue-10", "name": "Win a 10-cluster of blue owls", "event": "clusterWin", "symbol": "blue_owl", "minCount": 10, "target": 1}, ...]
+func LoadMissions(path string) ([]Mission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading missions: %w", err)
+	}
+	var missions []Mission
+	if err := json.Unmarshal(data, &missions); err != nil {
+		return nil, fmt.Errorf("parsing missions %s: %w", path, err)
+	}
+	if err := ValidateMissions(missions); err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// ValidateMissions checks that missions have unique IDs, a name, a positive target and an event
+// with filters that can match it
+func ValidateMissions(missions []Mission) error {
+	ids := make(map[string]bool)
+	for i, m := range missions {
+		switch {
+		case m.ID == "":
+			return fmt.Errorf("mission %d has no id", i)
+		case ids[m.ID]:
+			return fmt.Errorf("mission %q is defined twice", m.ID)
+		case m.Name == "":
+			return fmt.Errorf("mission %q has no name", m.ID)
+		case m.Target < 1:
+			return fmt.Errorf("mission %q target %d is not positive", m.ID, m.Target)
+		}
+		ids[m.ID] = true
+
+		if m.Level != 0 {
+			if err := m.Level.ValidateLevel(); err != nil {
+				return fmt.Errorf("mission %q: %w", m.ID, err)
+			}
+		}
+		switch m.Event {
+		case EventClusterWin:
+			if m.Symbol != "" && !IsRegularBirdSymbol(m.Symbol) {
+				return fmt.Errorf("mission %q: %q is not a regular bird symbol", m.ID, m.Symbol)
+			}
+			if m.MinCount != 0 && m.MinCount < Level1.GetMinConnection() {
+				return fmt.Errorf("mission %q minCount %d is below the smallest paying cluster", m.ID, m.MinCount)
+			}
+		case EventStageCleared:
+			if m.Symbol != "" && !IsStageClearedSymbol(m.Symbol) {
+				return fmt.Errorf("mission %q: %q is not a stage-cleared symbol", m.ID, m.Symbol)
+			}
+		case EventLevelAdvanced, EventFreeSpinsTriggered:
+			if m.Symbol != "" {
+				return fmt.Errorf("mission %q: %s events have no symbol", m.ID, m.Event)
+			}
+		default:
+			return fmt.Errorf("mission %q has unknown event %q", m.ID, m.Event)
+		}
+		if m.MinCount != 0 && m.Event != EventClusterWin {
+			return fmt.Errorf("mission %q: minCount only applies to %s", m.ID, EventClusterWin)
+		}
+	}
+	return nil
+}
+
+// missionState is a player's progress on one mission
+type missionState struct {
+	Progress    int        `json:"progress"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// Missions evaluates the missions against the game events of every player's steps. Progress is
+// held in memory and, when a progress file is set, saved to it periodically and loaded back at
+// startup, so at most the progress since the last save is lost in a crash. The file is per
+// instance.
+type Missions struct {
+	mu       sync.Mutex
+	missions []Mission
+	progress map[string]map[string]*missionState // By player, then mission ID
+	path     string                              // File progress is saved to; empty keeps it in memory only
+	dirty    bool                                // Progress changed since the last save
+	now      func() time.Time
+}
+
+// NewMissions creates the mission engine, loading saved progress from path when the file exists
+func NewMissions(missions []Mission, path string) (*Missions, error) {
+	m := &Missions{
+		missions: missions,
+		progress: make(map[string]map[string]*missionState),
+		path:     path,
+		now:      time.Now,
+	}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading mission progress: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.progress); err != nil {
+		return nil, fmt.Errorf("parsing mission progress %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// progressOf describes a player's progress on a mission
+func progressOf(mission Mission, state *missionState) MissionProgress {
+	p := MissionProgress{ID: mission.ID, Name: mission.Name, Event: mission.Event, Target: mission.Target}
+	if state != nil {
+		p.Progress = state.Progress
+		if state.CompletedAt != nil {
+			p.Completed = true
+			p.CompletedAt = state.CompletedAt.Unix()
+		}
+	}
+	return p
+}
+
+// consume applies a step's events to the player's missions and returns the missions they
+// progressed; a completed mission no longer progresses
+func (m *Missions) consume(clientID, playerID string, events []GameEvent) []MissionProgress {
+	if len(events) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	var updates []MissionProgress
+	for _, mission := range m.missions {
+		state := m.progress[player][mission.ID]
+		if state != nil && state.CompletedAt != nil {
+			continue
+		}
+		gained := 0
+		for _, e := range events {
+			gained += mission.progressFrom(e)
+		}
+		if gained == 0 {
+			continue
+		}
+
+		if state == nil {
+			if m.progress[player] == nil {
+				m.progress[player] = make(map[string]*missionState)
+			}
+			state = &missionState{}
+			m.progress[player][mission.ID] = state
+		}
+		state.Progress = min(state.Progress+gained, mission.Target)
+		if state.Progress == mission.Target {
+			completedAt := m.now()
+			state.CompletedAt = &completedAt
+		}
+		m.dirty = true
+		updates = append(updates, progressOf(mission, state))
+	}
+	return updates
+}
+
+// List returns the player's progress on every mission
+func (m *Missions) List(clientID, playerID string) []MissionProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	missions := make([]MissionProgress, 0, len(m.missions))
+	for _, mission := range m.missions {
+		missions = append(missions, progressOf(mission, m.progress[player][mission.ID]))
+	}
+	return missions
+}
+
+// Save writes the progress of every player to the progress file if it changed since the last save.
+// The file is replaced in one rename, so a crash while saving leaves the previous save intact.
+func (m *Missions) Save() error {
+	m.mu.Lock()
+	if m.path == "" || !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(m.progress)
+	m.dirty = false
+	m.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(m.path, data)
+	}
+	if err != nil {
+		m.mu.Lock()
+		m.dirty = true
+		m.mu.Unlock()
+		return fmt.Errorf("saving mission progress: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data through a temporary file in the same directory
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RunPersistence saves mission progress every interval; it never returns
+func (m *Missions) RunPersistence(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := m.Save(); err != nil {
+			log.Printf("Mission progress not saved: %v", err)
+		}
+	}
+}
+
+// trackMissions feeds a step's events to the mission engine and returns the missions they
+// progressed; it is a no-op when missions are disabled
+func (rg *RouteGroup) trackMissions(env requestEnv, events gameEvents) []MissionProgress {
+	if rg.Missions == nil {
+		return nil
+	}
+	updates := rg.Missions.consume(env.Session.ClientID, env.Session.PlayerID, events)
+	for _, u := range updates {
+		if u.Completed {
+			log.Printf("Player %s of %s completed mission %s", env.Session.PlayerID, env.Session.ClientID, u.ID)
+		}
+	}
+	return updates
+}
+
+// MissionsHandler handles the /missions/birdsparty endpoint
+// Returns the player's progress on every mission
+func (rg *RouteGroup) MissionsHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+
+	resp := MissionsResponse{Status: "success", Message: "", Missions: []MissionProgress{}}
+	if rg.Missions != nil {
+		resp.Missions = rg.Missions.List(env.Session.ClientID, env.Session.PlayerID)
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,189 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+)
+
+// PayBand pays Credits (for Bet Multiplier = 1) for every cluster of MinCount to MaxCount
+// connected symbols. A MaxCount of 0 makes the band open-ended.
+type PayBand struct {
+	MinCount int     `json:"min"`
+	MaxCount int     `json:"max,omitempty"`
+	Credits  float64 `json:"credits"`
+}
+
+// contains reports whether a cluster of count symbols falls in the band
+func (b PayBand) contains(count int) bool {
+	return count >= b.MinCount && (b.MaxCount == 0 || count <= b.MaxCount)
+}
+
+// ClusterBonus multiplies the win of a step that pays at least MinClusters connections at once
+type ClusterBonus struct {
+	MinClusters int     `json:"minClusters"`
+	Multiplier  float64 `json:"multiplier"`
+}
+
+// PayModifiers are symbol-agnostic multipliers applied on top of the size bands on every level
+type PayModifiers struct {
+	// CascadeMultipliers is the base game cascade ladder: entry i multiplies the connections paid at
+	// cascade level i, where 0 is the spin or a new level grid, 1 the first refill and so on. The last
+	// entry applies to deeper cascades.
+	CascadeMultipliers []float64 `json:"cascadeMultipliers,omitempty"`
+	// FreeSpinsCascadeMultipliers is the cascade ladder of free spins; empty uses CascadeMultipliers
+	FreeSpinsCascadeMultipliers []float64 `json:"freeSpinsCascadeMultipliers,omitempty"`
+	// MultiClusterBonus holds bonuses by ascending MinClusters; the highest one reached applies
+	MultiClusterBonus []ClusterBonus `json:"multiClusterBonus,omitempty"`
+}
+
+// cascadeMultiplier returns the multiplier at step cascadeLevel of the game mode's cascade ladder
+func (m PayModifiers) cascadeMultiplier(mode string, cascadeLevel int) float64 {
+	ladder := m.CascadeMultipliers
+	if mode == "freeSpins" && len(m.FreeSpinsCascadeMultipliers) > 0 {
+		ladder = m.FreeSpinsCascadeMultipliers
+	}
+	if len(ladder) == 0 {
+		return 1
+	}
+	return ladder[min(cascadeLevel, len(ladder)-1)]
+}
+
+// clusterBonus returns the multi-cluster bonus multiplier for a step paying clusters connections
+func (m PayModifiers) clusterBonus(clusters int) float64 {
+	multiplier := 1.0
+	for _, bonus := range m.MultiClusterBonus {
+		if clusters >= bonus.MinClusters {
+			multiplier = bonus.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// Paytable prices clusters by size band for each level and regular bird symbol
+type Paytable struct {
+	Levels    map[Level]map[Symbol][]PayBand `json:"levels"`
+	Modifiers PayModifiers                   `json:"modifiers"`
+}
+
+// DefaultPaytable is the certified paytable, without modifiers.
+// Only regular bird symbols have payouts, stage-cleared symbols don't pay.
+var DefaultPaytable = Paytable{
+	Levels: map[Level]map[Symbol][]PayBand{
+		Level1: { // 4x4 grid, clusters of 4 or more
+			SymbolPurpleOwl: {{4, 4, 2}, {5, 5, 4}, {6, 6, 5}, {7, 7, 8}, {8, 8, 10}, {9, 9, 20}, {10, 10, 30}, {11, 11, 50}, {12, 12, 100}, {13, 13, 200}, {14, 0, 400}},
+			SymbolGreenOwl:  {{4, 4, 4}, {5, 5, 5}, {6, 6, 10}, {7, 7, 20}, {8, 8, 30}, {9, 9, 50}, {10, 10, 100}, {11, 11, 250}, {12, 12, 500}, {13, 13, 750}, {14, 0, 800}},
+			SymbolYellowOwl: {{4, 4, 5}, {5, 5, 10}, {6, 6, 20}, {7, 7, 40}, {8, 8, 80}, {9, 9, 160}, {10, 10, 500}, {11, 11, 1000}, {12, 12, 2000}, {13, 13, 5000}, {14, 0, 6000}},
+			SymbolBlueOwl:   {{4, 4, 10}, {5, 5, 30}, {6, 6, 50}, {7, 7, 60}, {8, 8, 100}, {9, 9, 750}, {10, 10, 1000}, {11, 11, 10000}, {12, 12, 20000}, {13, 13, 50000}, {14, 0, 60000}},
+			SymbolRedOwl:    {{4, 4, 20}, {5, 5, 50}, {6, 6, 100}, {7, 7, 500}, {8, 8, 1000}, {9, 9, 2000}, {10, 10, 5000}, {11, 11, 20000}, {12, 12, 50000}, {13, 13, 60000}, {14, 0, 80000}},
+		},
+		Level2: { // 5x5 grid, clusters of 5 or more
+			SymbolPurpleOwl: {{5, 5, 2}, {6, 6, 4}, {7, 7, 5}, {8, 8, 8}, {9, 9, 10}, {10, 10, 20}, {11, 11, 30}, {12, 12, 50}, {13, 13, 100}, {14, 14, 200}, {15, 0, 450}},
+			SymbolGreenOwl:  {{5, 5, 4}, {6, 6, 5}, {7, 7, 10}, {8, 8, 20}, {9, 9, 30}, {10, 10, 50}, {11, 11, 100}, {12, 12, 250}, {13, 13, 500}, {14, 14, 750}, {15, 0, 1000}},
+			SymbolYellowOwl: {{5, 5, 5}, {6, 6, 10}, {7, 7, 20}, {8, 8, 40}, {9, 9, 80}, {10, 10, 160}, {11, 11, 500}, {12, 12, 1000}, {13, 13, 2000}, {14, 14, 5000}, {15, 0, 7000}},
+			SymbolBlueOwl:   {{5, 5, 10}, {6, 6, 30}, {7, 7, 50}, {8, 8, 60}, {9, 9, 100}, {10, 10, 750}, {11, 11, 1000}, {12, 12, 10000}, {13, 13, 20000}, {14, 14, 50000}, {15, 0, 70000}},
+			SymbolRedOwl:    {{5, 5, 20}, {6, 6, 50}, {7, 7, 100}, {8, 8, 500}, {9, 9, 1000}, {10, 10, 2000}, {11, 11, 5000}, {12, 12, 20000}, {13, 13, 50000}, {14, 14, 80000}, {15, 0, 100000}},
+		},
+		Level3: { // 6x6 grid, clusters of 6 or more
+			SymbolPurpleOwl: {{6, 6, 2}, {7, 7, 4}, {8, 8, 5}, {9, 9, 8}, {10, 10, 10}, {11, 11, 20}, {12, 12, 30}, {13, 13, 50}, {14, 14, 100}, {15, 15, 200}, {16, 0, 500}},
+			SymbolGreenOwl:  {{6, 6, 4}, {7, 7, 5}, {8, 8, 10}, {9, 9, 20}, {10, 10, 30}, {11, 11, 50}, {12, 12, 100}, {13, 13, 250}, {14, 14, 500}, {15, 15, 750}, {16, 0, 1200}},
+			SymbolYellowOwl: {{6, 6, 5}, {7, 7, 10}, {8, 8, 20}, {9, 9, 40}, {10, 10, 80}, {11, 11, 160}, {12, 12, 500}, {13, 13, 1000}, {14, 14, 2000}, {15, 15, 5000}, {16, 0, 8000}},
+			SymbolBlueOwl:   {{6, 6, 10}, {7, 7, 30}, {8, 8, 50}, {9, 9, 60}, {10, 10, 100}, {11, 11, 750}, {12, 12, 1000}, {13, 13, 10000}, {14, 14, 20000}, {15, 15, 50000}, {16, 0, 80000}},
+			SymbolRedOwl:    {{6, 6, 20}, {7, 7, 50}, {8, 8, 100}, {9, 9, 500}, {10, 10, 1000}, {11, 11, 2000}, {12, 12, 5000}, {13, 13, 20000}, {14, 14, 50000}, {15, 0, 100000}},
+		},
+	},
+}
+
+// LoadPaytable reads and validates a paytable from a JSON file. Levels the file leaves out keep
+// the bands of DefaultPaytable, so a file may only set modifiers:
+//
+//	{"levels": {"1": {"purple_owl": [{"min": 4, "max": 4, "credits": 2}, ..., {"min": 14, "credits": 400}], ...}, ...},
+//	 "modifiers": {"cascadeMultipliers": [1, 2, 3, 5], "freeSpinsCascadeMultipliers": [2, 4, 6, 10],
+//	               "multiClusterBonus": [{"minClusters": 2, "multiplier": 1.5}]}}
+func LoadPaytable(path string) (*Paytable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading paytable: %w", err)
+	}
+	paytable := Paytable{Levels: maps.Clone(DefaultPaytable.Levels)}
+	if err := json.Unmarshal(data, &paytable); err != nil {
+		return nil, fmt.Errorf("parsing paytable %s: %w", path, err)
+	}
+	if err := paytable.Validate(); err != nil {
+		return nil, err
+	}
+	return &paytable, nil
+}
+
+// Validate checks that every level prices every regular bird symbol with whole, non-negative
+// credits in bands that run without gaps or overlaps from the level's minimum connection to
+// the full grid, and that the modifiers are positive
+func (p *Paytable) Validate() error {
+	for level, symbols := range p.Levels {
+		if err := level.ValidateLevel(); err != nil {
+			return fmt.Errorf("paytable: %w", err)
+		}
+		for symbol := range symbols {
+			if !IsRegularBirdSymbol(symbol) {
+				return fmt.Errorf("paytable level %d: %q is not a regular bird symbol", level, symbol)
+			}
+		}
+	}
+	for _, level := range []Level{Level1, Level2, Level3} {
+		for _, symbol := range []Symbol{SymbolPurpleOwl, SymbolGreenOwl, SymbolYellowOwl, SymbolBlueOwl, SymbolRedOwl} {
+			if err := validatePayBands(p.Levels[level][symbol], level); err != nil {
+				return fmt.Errorf("paytable level %d %s: %w", level, symbol, err)
+			}
+		}
+	}
+
+	for i, multiplier := range p.Modifiers.CascadeMultipliers {
+		if multiplier <= 0 {
+			return fmt.Errorf("paytable cascadeMultipliers[%d] %v must be positive", i, multiplier)
+		}
+	}
+	for i, multiplier := range p.Modifiers.FreeSpinsCascadeMultipliers {
+		if multiplier <= 0 {
+			return fmt.Errorf("paytable freeSpinsCascadeMultipliers[%d] %v must be positive", i, multiplier)
+		}
+	}
+	for i, bonus := range p.Modifiers.MultiClusterBonus {
+		switch {
+		case bonus.MinClusters < 2:
+			return fmt.Errorf("paytable multiClusterBonus[%d] minClusters %d must be at least 2", i, bonus.MinClusters)
+		case i > 0 && bonus.MinClusters <= p.Modifiers.MultiClusterBonus[i-1].MinClusters:
+			return fmt.Errorf("paytable multiClusterBonus[%d] minClusters %d must be above the previous bonus", i, bonus.MinClusters)
+		case bonus.Multiplier <= 0:
+			return fmt.Errorf("paytable multiClusterBonus[%d] multiplier %v must be positive", i, bonus.Multiplier)
+		}
+	}
+	return nil
+}
+
+// validatePayBands checks that bands cover every cluster size possible on the level exactly once
+func validatePayBands(bands []PayBand, level Level) error {
+	if len(bands) == 0 {
+		return fmt.Errorf("no pay bands")
+	}
+	gridArea := level.GetGridSize() * level.GetGridSize()
+	next := level.GetMinConnection()
+	for i, band := range bands {
+		switch {
+		case band.MinCount != next:
+			return fmt.Errorf("band %d starts at %d, expected %d", i, band.MinCount, next)
+		case band.MaxCount == 0 && i != len(bands)-1:
+			return fmt.Errorf("band %d is open-ended but is not the last band", i)
+		case band.MaxCount != 0 && band.MaxCount < band.MinCount:
+			return fmt.Errorf("band %d ends at %d before it starts at %d", i, band.MaxCount, band.MinCount)
+		case band.Credits < 0 || band.Credits != math.Trunc(band.Credits):
+			return fmt.Errorf("band %d credits %v must be a whole non-negative number", i, band.Credits)
+		}
+		next = band.MaxCount + 1
+	}
+	if last := bands[len(bands)-1]; last.MaxCount != 0 && last.MaxCount < gridArea {
+		return fmt.Errorf("bands end at %d, clusters of up to %d fit the grid", last.MaxCount, gridArea)
+	}
+	return nil
+}
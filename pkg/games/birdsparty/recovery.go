@@ -0,0 +1,95 @@
+package birdsparty
+
+import (
+	"log"
+	"time"
+)
+
+// Round recovery modes, applied when a player with an unfinished round launches a new session
+const (
+	RecoveryResume   = "resume"   // Return the unfinished round for the client to resume
+	RecoveryComplete = "complete" // Complete the round on the server and report the result
+)
+
+// completeRound plays the remaining steps of an unfinished round on the server, with the session
+// and caller that started it. Every step is audited and counted like a step played by the client.
+func (rg *RouteGroup) completeRound(round unfinishedRound) (RecoveredRound, error) {
+	env, err := rg.resolveSession(round.session)
+	if err != nil {
+		return RecoveredRound{}, err
+	}
+
+	progress := roundProgress{Steps: round.Steps}
+	hasStageCleared := len(round.GameState.StageClearedSymbols) > 0
+	gs, err := rg.playRoundSteps(env, round.GameState, hasStageCleared, round.BetID, round.client, &progress)
+	if err != nil {
+		return RecoveredRound{}, err
+	}
+
+	return RecoveredRound{
+		BetID:      round.BetID,
+		Currency:   env.Currency.Code,
+		Win:        env.Currency.ToMajor(progress.Win),
+		JackpotWin: env.Currency.ToMajor(progress.JackpotWin),
+		Steps:      progress.Steps - round.Steps,
+		GameState:  gs,
+		SettledAt:  time.Now().Unix(),
+	}, nil
+}
+
+// settleRound completes an unfinished round and keeps the result for the player's next launch
+func (rg *RouteGroup) settleRound(round unfinishedRound, reason string) error {
+	recovered, err := rg.completeRound(round)
+	if err != nil {
+		log.Printf("Failed to complete round %s of player %s (%s): %v", round.BetID, round.session.PlayerID, reason, err)
+		return err
+	}
+	rg.Rounds.addRecovered(round.session.ClientID, round.session.PlayerID, recovered)
+	log.Printf("Round %s of player %s completed by the server (%s): steps=%d win=%.2f %s",
+		round.BetID, round.session.PlayerID, reason, recovered.Steps, recovered.Win, recovered.Currency)
+	return nil
+}
+
+// recoverOnLaunch handles the player's unfinished round when a new session starts. The round is
+// completed on the server in RecoveryComplete mode or once it is past RoundSettleTimeout, and
+// otherwise returned for the client to resume. Rounds completed since the last launch are returned too.
+func (rg *RouteGroup) recoverOnLaunch(clientID, playerID string) (*Round, []RecoveredRound) {
+	if rg.Rounds == nil {
+		return nil, nil
+	}
+
+	var resume *Round
+	if round, ok := rg.Rounds.unfinished(clientID, playerID); ok {
+		expired := rg.RoundSettleTimeout > 0 && time.Since(time.Unix(round.UpdatedAt, 0)) > rg.RoundSettleTimeout
+		completed := false
+		if expired {
+			completed = rg.settleRound(round, "timed out") == nil
+		} else if rg.RoundRecovery == RecoveryComplete {
+			completed = rg.settleRound(round, "launch") == nil
+		}
+		// The client resumes the round, also when the server could not complete it
+		if !completed {
+			resume = &round.Round
+		}
+	}
+	return resume, rg.Rounds.takeRecovered(clientID, playerID)
+}
+
+// SettleExpiredRounds completes every round left unfinished for longer than RoundSettleTimeout
+func (rg *RouteGroup) SettleExpiredRounds() {
+	if rg.Rounds == nil || rg.RoundSettleTimeout <= 0 {
+		return
+	}
+	for _, round := range rg.Rounds.expired(time.Now().Add(-rg.RoundSettleTimeout)) {
+		rg.settleRound(round, "timed out")
+	}
+}
+
+// RunRoundRecovery calls SettleExpiredRounds every interval; it never returns
+func (rg *RouteGroup) RunRoundRecovery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rg.SettleExpiredRounds()
+	}
+}
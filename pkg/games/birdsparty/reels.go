@@ -0,0 +1,241 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/tenant"
+)
+
+// StripSet holds the reel strips of one math variant: one strip per grid column, by level and
+// game mode ("base" or "freeSpins"). Strips are read top to bottom and wrap around.
+type StripSet map[Level]map[string][][]Symbol
+
+// LoadReelStrips reads and validates reel strips by math variant from a JSON file:
+//
+//	{"certified-v1": {"1": {"base": [["purple_owl", "red_owl", ...], ...], "freeSpins": [...]}, "2": {...}, "3": {...}}}
+func LoadReelStrips(path string) (map[string]StripSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading reel strips: %w", err)
+	}
+	var variants map[string]StripSet
+	if err := json.Unmarshal(data, &variants); err != nil {
+		return nil, fmt.Errorf("parsing reel strips %s: %w", path, err)
+	}
+	for variant, strips := range variants {
+		if err := strips.Validate(); err != nil {
+			return nil, fmt.Errorf("reel strips of math variant %q: %w", variant, err)
+		}
+	}
+	return variants, nil
+}
+
+// Validate checks that every level has strips for both game modes, one per grid column, each
+// at least as long as a column and holding only symbols that can appear on the level and mode
+func (s StripSet) Validate() error {
+	for _, level := range []Level{Level1, Level2, Level3} {
+		gridSize := level.GetGridSize()
+		for _, mode := range []string{"base", "freeSpins"} {
+			strips, ok := s[level][mode]
+			if !ok {
+				return fmt.Errorf("level %d has no %s strips", level, mode)
+			}
+			if len(strips) != gridSize {
+				return fmt.Errorf("level %d %s has %d strips, the %dx%d grid needs %d", level, mode, len(strips), gridSize, gridSize, gridSize)
+			}
+			for x, strip := range strips {
+				if len(strip) < gridSize {
+					return fmt.Errorf("level %d %s strip %d has %d symbols, at least %d are needed", level, mode, x, len(strip), gridSize)
+				}
+				for i, symbol := range strip {
+					switch {
+					case IsRegularBirdSymbol(symbol), symbol == level.GetStageClearedSymbol():
+					case symbol == SymbolFreeGame && mode == "base":
+					default:
+						return fmt.Errorf("level %d %s strip %d position %d: %q cannot appear on this level and mode", level, mode, x, i, symbol)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Reels are the strips a grid is drawn from and the stop of each column. A nil *Reels means
+// every cell is drawn independently from the symbol weight tables.
+type Reels struct {
+	Mode   string     // Game mode whose strips are used
+	Strips [][]Symbol // One strip per column
+	Stops  []int      // Strip index shown in the top row of each column
+}
+
+// spin draws a stop for every column and returns the grid the stops show
+func (reels *Reels) spin(r Source) [][]string {
+	gridSize := len(reels.Strips)
+	grid := make([][]string, gridSize)
+	for y := range grid {
+		grid[y] = make([]string, gridSize)
+	}
+	reels.Stops = make([]int, gridSize)
+	for x, strip := range reels.Strips {
+		reels.Stops[x] = r.Intn(len(strip))
+		for y := 0; y < gridSize; y++ {
+			grid[y][x] = string(strip[(reels.Stops[x]+y)%len(strip)])
+		}
+	}
+	return grid
+}
+
+// feed moves the stop of column up by count and returns the symbols that come into view, top to bottom
+func (reels *Reels) feed(column, count int) []Symbol {
+	strip := reels.Strips[column]
+	stop := ((reels.Stops[column]-count)%len(strip) + len(strip)) % len(strip)
+	reels.Stops[column] = stop
+
+	symbols := make([]Symbol, count)
+	for i := range symbols {
+		symbols[i] = strip[(stop+i)%len(strip)]
+	}
+	return symbols
+}
+
+// allRows returns the rows of every column that show the strip from its stop: the whole grid after a spin
+func (reels *Reels) allRows() map[int]int {
+	rows := make(map[int]int)
+	for x := range reels.Strips {
+		rows[x] = len(reels.Strips)
+	}
+	return rows
+}
+
+// refilledRows returns the rows of each refilled column that show the strip from its stop: the top
+// rows fed by gravity, given the positions that were refilled
+func refilledRows(positions []Position) map[int]int {
+	rows := make(map[int]int)
+	for _, pos := range positions {
+		rows[pos.X] = max(rows[pos.X], pos.Y+1)
+	}
+	return rows
+}
+
+// restop looks for reel stops that bring cost down to 0. The top rows[x] rows of column x show its
+// strip from the stop, and each move sets one column to the stop that lowers cost most. Only stops
+// are picked, never symbols written, so the grid remains what the strips show. It returns the grid
+// of the stops found, or false when no move lowers cost before it reaches 0; the reels then keep
+// their stops.
+func (reels *Reels) restop(grid [][]string, rows map[int]int, cost func([][]string) int) ([][]string, bool) {
+	grid = cloneGrid(grid)
+	stops := slices.Clone(reels.Stops)
+	best := cost(grid)
+	for improved := true; best > 0 && improved; {
+		improved = false
+		for _, x := range slices.Sorted(maps.Keys(rows)) {
+			strip := reels.Strips[x]
+			for d := 1; d < len(strip) && best > 0; d++ {
+				stop := (stops[x] + d) % len(strip)
+				showStop(grid, strip, x, stop, rows[x])
+				if c := cost(grid); c < best {
+					best, stops[x], improved = c, stop, true
+				}
+			}
+			showStop(grid, strip, x, stops[x], rows[x])
+		}
+	}
+	if best > 0 {
+		return nil, false
+	}
+	copy(reels.Stops, stops)
+	return grid, true
+}
+
+// showStop writes the top rows of column x as the strip shows them from stop
+func showStop(grid [][]string, strip []Symbol, x, stop, rows int) {
+	for y := 0; y < rows; y++ {
+		grid[y][x] = string(strip[(stop+y)%len(strip)])
+	}
+}
+
+// connectedCells returns a cost counting the cells of the grid's connections on level; 0 is a loss
+func connectedCells(level Level) func([][]string) int {
+	return func(grid [][]string) int {
+		cells := 0
+		for _, connection := range FindRegularConnections(grid, level) {
+			cells += connection.Count
+		}
+		return cells
+	}
+}
+
+// forceWin picks new stops that show a winning grid; when the strips have none within reach it
+// returns the grid of the stops drawn
+func (reels *Reels) forceWin(level Level, r Source) [][]string {
+	grid := reels.spin(r)
+	noWin := func(grid [][]string) int {
+		if len(FindRegularConnections(grid, level)) > 0 {
+			return 0
+		}
+		return 1
+	}
+	if won, ok := reels.restop(grid, reels.allRows(), noWin); ok {
+		return won
+	}
+	log.Printf("No winning reel stops found, keeping the stops drawn")
+	return grid
+}
+
+// forceLoss picks new stops that show a grid without connections; when the strips have none within
+// reach it returns the grid of the stops drawn
+func (reels *Reels) forceLoss(level Level, r Source) [][]string {
+	grid := reels.spin(r)
+	if lost, ok := reels.restop(grid, reels.allRows(), connectedCells(level)); ok {
+		return lost
+	}
+	log.Printf("No losing reel stops found, keeping the stops drawn")
+	return grid
+}
+
+// state returns the reel position carried in the game state; nil for weighted draws
+func (reels *Reels) state() *ReelState {
+	if reels == nil {
+		return nil
+	}
+	return &ReelState{Mode: reels.Mode, Stops: reels.Stops}
+}
+
+// reelsFor returns the reels a step draws from for the tenant's math variant, or nil when the
+// variant uses the symbol weight tables. A spin (newStops) draws new stops for the game state's
+// level and mode; other steps continue from the reel position in the game state, which is the
+// server's round state whenever rounds are tracked.
+func (rg *RouteGroup) reelsFor(t tenant.Tenant, gameState GameState, newStops bool) (*Reels, error) {
+	strips, ok := rg.ReelStrips[t.MathVariant]
+	if !ok {
+		return nil, nil
+	}
+	if newStops {
+		return &Reels{Mode: gameState.GameMode, Strips: strips[gameState.CurrentLevel][gameState.GameMode]}, nil
+	}
+
+	state := gameState.Reels
+	if state == nil {
+		return nil, apierror.New(apierror.InvalidGameState, "reels is required for reel-strip play")
+	}
+	columns, ok := strips[gameState.CurrentLevel][state.Mode]
+	if !ok {
+		return nil, invalidGameState("reels.mode %q has no strips", state.Mode)
+	}
+	if len(state.Stops) != len(columns) {
+		return nil, invalidGameState("reels.stops has %d stops, level %d has %d reels", len(state.Stops), gameState.CurrentLevel, len(columns))
+	}
+	for x, stop := range state.Stops {
+		if stop < 0 || stop >= len(columns[x]) {
+			return nil, invalidGameState("reels.stops[%d] %d is outside strip %d of length %d", x, stop, x, len(columns[x]))
+		}
+	}
+	return &Reels{Mode: state.Mode, Strips: columns, Stops: append([]int(nil), state.Stops...)}, nil
+}
@@ -0,0 +1,209 @@
+package birdsparty
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/rng"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/tenant"
+)
+
+// Source is the random number source the game engine draws from. Every random decision of a
+// step goes through it in a defined order, so replaying the same draws reproduces the step.
+// *rand.Rand satisfies it.
+type Source interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+// recordingSource draws from src and appends every draw to draws
+type recordingSource struct {
+	src   Source
+	draws *[]Draw
+}
+
+func (s *recordingSource) Float64() float64 {
+	v := s.src.Float64()
+	*s.draws = append(*s.draws, Draw{Value: v})
+	return v
+}
+
+func (s *recordingSource) Intn(n int) int {
+	v := s.src.Intn(n)
+	*s.draws = append(*s.draws, Draw{N: n, Value: float64(v)})
+	return v
+}
+
+// replaySource returns recorded draws in order. The first draw that does not match the
+// recording is kept in err; later draws return zero values.
+type replaySource struct {
+	draws []Draw
+	next  int
+	err   error
+}
+
+func (s *replaySource) take(n int) float64 {
+	if s.err != nil {
+		return 0
+	}
+	if s.next >= len(s.draws) {
+		s.err = fmt.Errorf("draw log exhausted after %d draws", len(s.draws))
+		return 0
+	}
+	d := s.draws[s.next]
+	if d.N != n {
+		s.err = fmt.Errorf("draw %d was recorded with n=%d, replayed with n=%d", s.next, d.N, n)
+		return 0
+	}
+	s.next++
+	return d.Value
+}
+
+func (s *replaySource) Float64() float64 {
+	return s.take(0)
+}
+
+func (s *replaySource) Intn(n int) int {
+	return int(s.take(n))
+}
+
+// stepTrace records what a game step consumes: the game state it was sent, the operator
+// settings, the RNG service outcomes and every random draw
+type stepTrace struct {
+	replay StepReplay
+	source Source
+}
+
+// newStepTrace starts recording a step that draws from a freshly seeded generator
+func newStepTrace(step RoundStep, gameState GameState) *stepTrace {
+	t := &stepTrace{replay: StepReplay{Step: step, GameState: cloneGameState(gameState)}}
+	t.source = &recordingSource{src: rand.New(rand.NewSource(time.Now().UnixNano())), draws: &t.replay.Draws}
+	return t
+}
+
+// providers wraps the step's RNG and settings providers so their answers are recorded
+func (t *stepTrace) providers(outcomes OutcomeProvider, settingsProvider SettingsProvider) (OutcomeProvider, SettingsProvider) {
+	return tracedOutcomes{outcomes, t}, tracedSettings{settingsProvider, t}
+}
+
+// finish completes the recording with the step's context and the grid it produced
+func (t *stepTrace) finish(betID string, env requestEnv, maxWinMultiplier float64, gameState GameState) *StepReplay {
+	t.replay.BetID = betID
+	t.replay.Currency = env.Currency.Code
+	t.replay.MathVariant = env.Tenant.MathVariant
+	t.replay.MaxWinMultiplier = maxWinMultiplier
+	t.replay.Grid = cloneGrid(gameState.Grid)
+	return &t.replay
+}
+
+// tracedOutcomes records the outcome of every RNG service call
+type tracedOutcomes struct {
+	OutcomeProvider
+	trace *stepTrace
+}
+
+func (p tracedOutcomes) GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (rng.Response, error) {
+	resp, err := p.OutcomeProvider.GetOutcome(clientID, gameID, playerID, betID, rtp, payoutMultiplier, betAmount, currency, ipAddress, userAgent)
+	if err == nil {
+		p.trace.replay.Outcomes = append(p.trace.replay.Outcomes, resp.PrefOutcome)
+	}
+	return resp, err
+}
+
+// tracedSettings records the settings the step was played with
+type tracedSettings struct {
+	SettingsProvider
+	trace *stepTrace
+}
+
+func (p tracedSettings) GetSettings(clientID, gameID, playerID string) (settings.Settings, error) {
+	s, err := p.SettingsProvider.GetSettings(clientID, gameID, playerID)
+	if err == nil {
+		p.trace.replay.Settings = s
+	}
+	return s, err
+}
+
+// Replay re-executes a recorded step with its recorded settings, RNG outcomes and draws and
+// returns the game state it produces. reelStrips are the strips by math variant the step was
+// played with; nil replays it with the symbol weight tables. paytable is the paytable it was paid
+// with; nil uses DefaultPaytable. jackpots are the jackpot pools it was played with, whose random
+// triggers draw from the step's draws; nil replays it without jackpots. It fails when the step does not
+// consume exactly the recorded outcomes and draws, which means the engine no longer plays the
+// step the same way.
+func Replay(rec StepReplay, reelStrips map[string]StripSet, paytable *Paytable, jackpots []JackpotPool) (GameState, error) {
+	cur, ok := money.Lookup(rec.Currency)
+	if !ok {
+		return GameState{}, fmt.Errorf("unsupported currency %q", rec.Currency)
+	}
+
+	// Replay needs no tenant, session limits, round tracking or audit
+	rg := &RouteGroup{MaxWinMultiplier: rec.MaxWinMultiplier, ReelStrips: reelStrips, Paytable: paytable}
+	if jackpots != nil {
+		rg.Jackpots = NewJackpots(jackpots)
+	}
+	env := requestEnv{
+		Session:  session.Claims{ClientID: "replay", GameID: GameID, PlayerID: "replay"},
+		Tenant:   tenant.Tenant{MathVariant: rec.MathVariant},
+		Currency: cur,
+		RNG:      rng.NewScriptedOutcome(rec.Outcomes, false),
+		Settings: &settings.StaticProvider{Settings: rec.Settings},
+	}
+	source := &replaySource{draws: rec.Draws}
+	trace := &stepTrace{replay: StepReplay{Step: rec.Step}, source: source}
+	gameState := cloneGameState(rec.GameState)
+
+	var (
+		result GameState
+		err    error
+	)
+	switch rec.Step {
+	case StepSpin:
+		var resp SpinResponse
+		resp, err = rg.spin(env, SpinRequest{GameState: gameState, BetID: rec.BetID, freeRound: rec.FreeRound}, clientInfo{}, trace)
+		result = resp.GameState
+	case StepProcessStageCleared:
+		var resp ProcessStageClearedResponse
+		resp, err = rg.processStageCleared(env, ProcessStageClearedRequest{GameState: gameState, BetID: rec.BetID}, clientInfo{}, trace)
+		result = resp.GameState
+	case StepCascade:
+		var resp CascadeResponse
+		resp, err = rg.cascade(env, CascadeRequest{GameState: gameState, BetID: rec.BetID}, clientInfo{}, trace)
+		result = resp.GameState
+	default:
+		return GameState{}, fmt.Errorf("unknown step %q", rec.Step)
+	}
+
+	switch {
+	case source.err != nil:
+		return GameState{}, source.err
+	case err != nil:
+		return GameState{}, err
+	case source.next != len(rec.Draws):
+		return GameState{}, fmt.Errorf("step used %d of %d recorded draws", source.next, len(rec.Draws))
+	case len(trace.replay.Outcomes) != len(rec.Outcomes):
+		return GameState{}, fmt.Errorf("step used %d of %d recorded outcomes", len(trace.replay.Outcomes), len(rec.Outcomes))
+	}
+	return result, nil
+}
+
+// cloneGameState copies a game state so a step can modify its grid without touching the original
+func cloneGameState(gameState GameState) GameState {
+	gameState.Grid = cloneGrid(gameState.Grid)
+	gameState.LastConnections = slices.Clone(gameState.LastConnections)
+	gameState.StageClearedSymbols = slices.Clone(gameState.StageClearedSymbols)
+	return gameState
+}
+
+func cloneGrid(grid [][]string) [][]string {
+	clone := make([][]string, len(grid))
+	for i, row := range grid {
+		clone[i] = slices.Clone(row)
+	}
+	return clone
+}
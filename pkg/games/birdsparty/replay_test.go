@@ -0,0 +1,227 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/rng"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+)
+
+// replayEnv returns a session environment whose RNG service alternates wins and losses, so
+// recorded steps take both the paying and the losing paths
+func replayEnv() requestEnv {
+	env := testEnv("win", settings.Settings{RTP: 96})
+	env.RNG = rng.NewScriptedOutcome([]string{"win", "loss", "win"}, true)
+	return env
+}
+
+// stageClearedGame returns a level 1 game with stage-cleared symbols pending on its grid and the
+// given stage progress
+func stageClearedGame(progress int) GameState {
+	gs := newTestGame(0.1)
+	gs.Grid = GenerateGrid(Level1, "base", nil, rand.New(rand.NewSource(1)), true)
+	gs.Grid[0][0] = string(Level1.GetStageClearedSymbol())
+	gs.StageClearedSymbols = FindStageClearedSymbols(gs.Grid, Level1)
+	gs.StageProgress = progress
+	return gs
+}
+
+// checkReplay checks that the step recorded by trace, read back from JSON as the audit log
+// stores it, replays to the game state the step produced
+func checkReplay(t *testing.T, trace *stepTrace, played GameState, jackpots []JackpotPool) {
+	t.Helper()
+	data, err := json.Marshal(trace.replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec StepReplay
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Replay(rec, nil, nil, jackpots)
+	switch {
+	case err != nil:
+		t.Fatalf("replaying %s of %s: %v", rec.Step, rec.BetID, err)
+	case !reflect.DeepEqual(rec.Grid, played.Grid):
+		t.Fatalf("%s of %s recorded grid %v, played %v", rec.Step, rec.BetID, rec.Grid, played.Grid)
+	case !reflect.DeepEqual(got.Grid, played.Grid):
+		t.Fatalf("%s of %s replayed grid %v, played %v", rec.Step, rec.BetID, got.Grid, played.Grid)
+	case got.TotalWin != played.TotalWin || got.RoundWin != played.RoundWin:
+		t.Fatalf("%s of %s replayed totalWin=%v roundWin=%v, played %v, %v", rec.Step, rec.BetID, got.TotalWin, got.RoundWin, played.TotalWin, played.RoundWin)
+	case got.CurrentLevel != played.CurrentLevel || got.GameMode != played.GameMode || got.FreeSpins != played.FreeSpins:
+		t.Fatalf("%s of %s replayed level %d %s %+v, played level %d %s %+v", rec.Step, rec.BetID, got.CurrentLevel, got.GameMode, got.FreeSpins, played.CurrentLevel, played.GameMode, played.FreeSpins)
+	}
+}
+
+// playCascades plays and replays the cascades a step left pending, until stage-cleared symbols
+// interrupt the chain or it ends; it returns the number of cascades played
+func playCascades(t *testing.T, rg *RouteGroup, env requestEnv, gs GameState, seed int64, jackpots []JackpotPool) int {
+	t.Helper()
+	played := 0
+	for gs.Cascading && len(gs.StageClearedSymbols) == 0 && !gs.MaxWinReached {
+		trace := seededTrace(StepCascade, gs, seed*1000+int64(played))
+		resp, err := rg.cascade(env, CascadeRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReplay(t, trace, resp.GameState, jackpots)
+		gs = resp.GameState
+		played++
+	}
+	return played
+}
+
+func TestReplaySpinsAndCascades(t *testing.T) {
+	rg := &RouteGroup{}
+	env := replayEnv()
+	cascades := 0
+	for seed := int64(1); seed <= 40; seed++ {
+		gs := newTestGame(0.1)
+		trace := seededTrace(StepSpin, gs, seed)
+		spin, err := rg.spin(env, SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReplay(t, trace, spin.GameState, nil)
+		cascades += playCascades(t, rg, env, spin.GameState, seed, nil)
+	}
+	if cascades == 0 {
+		t.Fatal("no spin left a cascade to replay")
+	}
+}
+
+func TestReplayStageCleared(t *testing.T) {
+	rg := &RouteGroup{}
+	env := replayEnv()
+	tests := []struct {
+		name     string
+		progress int
+		advances bool
+	}{
+		{"refill", 0, false},
+		{"level advance", StageProgressTarget - 1, true},
+	}
+	for _, tt := range tests {
+		gs := stageClearedGame(tt.progress)
+		for seed := int64(1); seed <= 20; seed++ {
+			trace := seededTrace(StepProcessStageCleared, gs, seed)
+			resp, err := rg.processStageCleared(env, ProcessStageClearedRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if resp.LevelAdvanced != tt.advances {
+				t.Fatalf("%s: levelAdvanced = %v, want %v", tt.name, resp.LevelAdvanced, tt.advances)
+			}
+			checkReplay(t, trace, resp.GameState, nil)
+			playCascades(t, rg, env, resp.GameState, seed, nil)
+		}
+	}
+}
+
+func TestReplayFreeSpins(t *testing.T) {
+	rg := &RouteGroup{}
+	env := replayEnv()
+	gs := newTestGame(0.1)
+	gs.GameMode = "freeSpins"
+	gs.FreeSpins.Remaining, gs.FreeSpins.TotalAwarded, gs.FreeSpins.Multiplier = 5, FreeSpinsAwarded, 2.5
+
+	won := false
+	for seed := int64(1); seed <= 20; seed++ {
+		trace := seededTrace(StepSpin, gs, seed)
+		spin, err := rg.spin(env, SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spin.GameState.FreeSpins.Remaining != 4 || spin.TotalCost != 0 {
+			t.Fatalf("free spin left %d free spins at cost %v, want 4 at no cost", spin.GameState.FreeSpins.Remaining, spin.TotalCost)
+		}
+		won = won || spin.GameState.TotalWin > 0
+		checkReplay(t, trace, spin.GameState, nil)
+		playCascades(t, rg, env, spin.GameState, seed, nil)
+	}
+	if !won {
+		t.Fatal("no free spin paid a multiplied win to replay")
+	}
+}
+
+func TestReplayJackpots(t *testing.T) {
+	pools := []JackpotPool{
+		{Name: "mini", ContributionPercent: 1, Seeds: map[string]float64{"USD": 5}, Trigger: JackpotTrigger{RandomChance: 0.3}},
+		{Name: "major", ContributionPercent: 0.5, Seeds: map[string]float64{"USD": 50}, Trigger: JackpotTrigger{Cluster: &ClusterTrigger{Symbol: SymbolRedOwl, MinCount: 5}}},
+	}
+	rg := &RouteGroup{Jackpots: NewJackpots(pools)}
+	env := replayEnv()
+
+	wins := 0
+	for seed := int64(1); seed <= 40; seed++ {
+		gs := newTestGame(0.1)
+		trace := seededTrace(StepSpin, gs, seed)
+		spin, err := rg.spin(env, SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wins += len(spin.JackpotWins)
+		checkReplay(t, trace, spin.GameState, pools)
+		playCascades(t, rg, env, spin.GameState, seed, pools)
+	}
+	if wins == 0 {
+		t.Fatal("no spin won a jackpot")
+	}
+}
+
+func TestReplayFreeRoundJackpots(t *testing.T) {
+	pools := []JackpotPool{{Name: "mini", ContributionPercent: 1, Seeds: map[string]float64{"USD": 5}, Trigger: JackpotTrigger{RandomChance: 0.3}}}
+	rg := &RouteGroup{Jackpots: NewJackpots(pools)}
+	env := replayEnv()
+
+	// A free round stakes nothing, so it draws no random jackpot trigger, and neither may its replay
+	gs := newTestGame(0.1)
+	trace := seededTrace(StepSpin, gs, 1)
+	trace.replay.FreeRound = true
+	spin, err := rg.spin(env, SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1", freeRound: true}, clientInfo{}, trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spin.TotalCost != 0 {
+		t.Fatalf("free round cost %v", spin.TotalCost)
+	}
+	checkReplay(t, trace, spin.GameState, pools)
+
+	staked := trace.replay
+	staked.FreeRound = false
+	if _, err := Replay(staked, nil, nil, pools); err == nil {
+		t.Error("a free round replayed as a staked spin matched its draws")
+	}
+}
+
+func TestReplayRejectsChangedDraws(t *testing.T) {
+	rg := &RouteGroup{}
+	gs := newTestGame(0.1)
+	trace := seededTrace(StepSpin, gs, 1)
+	if _, err := rg.spin(replayEnv(), SpinRequest{GameState: cloneGameState(gs), BetID: "bet_1"}, clientInfo{}, trace); err != nil {
+		t.Fatal(err)
+	}
+	rec := trace.replay
+
+	short := rec
+	short.Draws = rec.Draws[:len(rec.Draws)-1]
+	if _, err := Replay(short, nil, nil, nil); err == nil {
+		t.Error("replay with a missing draw succeeded")
+	}
+
+	extra := rec
+	extra.Draws = append(append([]Draw(nil), rec.Draws...), Draw{Value: 0.5})
+	if _, err := Replay(extra, nil, nil, nil); err == nil {
+		t.Error("replay with an unused draw succeeded")
+	}
+
+	reordered := rec
+	reordered.Draws = append([]Draw{{N: 7, Value: 3}}, rec.Draws...)
+	if _, err := Replay(reordered, nil, nil, nil); err == nil {
+		t.Error("replay with a draw of another range succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+package birdsparty
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
+	"github.com/gofiber/fiber/v2"
+)
+
+// playerOf identifies the session's player for the responsible-gaming tracker
+func playerOf(env requestEnv) responsible.Player {
+	return responsible.Player{ClientID: env.Session.ClientID, PlayerID: env.Session.PlayerID}
+}
+
+// sessionOf identifies the session for the responsible-gaming tracker
+func sessionOf(env requestEnv) responsible.Session {
+	return responsible.Session{ID: env.Session.SessionID, Started: time.Unix(env.Session.IssuedAt, 0)}
+}
+
+// checkResponsibleGaming evaluates the tenant's policy and the player's own limits before a bet of stake
+func (rg *RouteGroup) checkResponsibleGaming(env requestEnv, stake money.Amount) error {
+	if rg.Responsible == nil {
+		return nil
+	}
+	return rg.Responsible.CheckBet(env.Tenant.ResponsibleGaming, playerOf(env), sessionOf(env), stake, env.Currency)
+}
+
+// PlayerLimitsHandler handles the /responsible-gaming/players endpoint
+// Called by the operator (HMAC-signed) to set a player's own limits and self-exclusion
+func (rg *RouteGroup) PlayerLimitsHandler(c *fiber.Ctx) error {
+	var req PlayerLimitsRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse player limits request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+	if req.PlayerID == "" {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "player_id is required"))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	if rg.Responsible == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Responsible gaming is not enabled"))
+	}
+
+	player := responsible.Player{ClientID: t.ClientID, PlayerID: req.PlayerID}
+	if err := rg.Responsible.SetPlayerLimits(player, req.Limits); err != nil {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, err.Error()))
+	}
+	if req.SelfExcludedUntil != nil {
+		rg.Responsible.SelfExclude(player, *req.SelfExcludedUntil)
+		log.Printf("Self-exclusion for player %s of %s set until %s", req.PlayerID, t.ClientID, req.SelfExcludedUntil.UTC().Format(time.RFC3339))
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "",
+	})
+}
+
+// DepositHandler handles the /responsible-gaming/deposits endpoint
+// Called by the operator (HMAC-signed) before accepting a deposit; the deposit is recorded
+// only if it stays within the deposit limits, otherwise DEPOSIT_LIMIT_REACHED is returned
+func (rg *RouteGroup) DepositHandler(c *fiber.Ctx) error {
+	var req DepositRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse deposit request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	if rg.Responsible == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Responsible gaming is not enabled"))
+	}
+
+	cur, ok := money.Lookup(strings.TrimSpace(req.Currency))
+	if !ok {
+		return apierror.Write(c, apierror.New(apierror.UnsupportedCurrency, ""))
+	}
+	amount, err := cur.FromMajor(req.Amount)
+	if req.PlayerID == "" || err != nil || amount <= 0 {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "player_id and a positive amount are required"))
+	}
+
+	player := responsible.Player{ClientID: t.ClientID, PlayerID: req.PlayerID}
+	if err := rg.Responsible.RecordDeposit(t.ResponsibleGaming, player, amount, cur); err != nil {
+		log.Printf("Deposit refused for player %s: %v", req.PlayerID, err)
+		return apierror.Write(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "success",
+		"message": "",
+	})
+}
+
+// RealityCheckHandler handles the /responsible-gaming/reality-check endpoint
+// Called by the game client once the player has seen the reality check; play can then continue
+func (rg *RouteGroup) RealityCheckHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+	if rg.Responsible == nil {
+		return c.JSON(RealityCheckResponse{Status: "success", Message: "", Session: map[string]interface{}{}})
+	}
+
+	summary := rg.Responsible.AcknowledgeRealityCheck(sessionOf(env), env.Currency)
+	log.Printf("Reality check acknowledged: session=%s player=%s", env.Session.SessionID, env.Session.PlayerID)
+
+	return c.JSON(RealityCheckResponse{
+		Status:  "success",
+		Message: "",
+		Session: summary,
+	})
+}
@@ -0,0 +1,328 @@
+package birdsparty
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
+	"github.com/gofiber/fiber/v2"
+)
+
+// settledRoundRetention is how long settled rounds are remembered to refuse reused bet IDs
+const settledRoundRetention = 24 * time.Hour
+
+// storedRound is a Round together with its bookkeeping
+type storedRound struct {
+	Round
+	busy bool // A step is being played
+
+	// The session and caller that started the round, so the server can finish it
+	session session.Claims
+	client  clientInfo
+}
+
+// RoundStore tracks every player's rounds and enforces the order of their steps:
+// spin, then process-stage-cleared while stage-cleared symbols are on the grid, then cascade
+// while connections remain. A player has at most one unsettled round, which can be resumed.
+// State is held in memory, so it is per instance and does not survive a restart.
+type RoundStore struct {
+	mu        sync.Mutex
+	rounds    map[string]*storedRound     // By player and bet ID
+	open      map[string]string           // Player to the bet ID of their unsettled round
+	last      map[string]GameState        // Player to the game state their last settled round ended with
+	recovered map[string][]RecoveredRound // Player to rounds settled by the server, not yet reported
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRoundStore creates an empty round store
+func NewRoundStore() *RoundStore {
+	return &RoundStore{
+		rounds:    make(map[string]*storedRound),
+		open:      make(map[string]string),
+		last:      make(map[string]GameState),
+		recovered: make(map[string][]RecoveredRound),
+		now:       time.Now,
+	}
+}
+
+func playerKey(clientID, playerID string) string {
+	return clientID + "|" + playerID
+}
+
+func roundKey(player, betID string) string {
+	return player + "|" + betID
+}
+
+// Begin checks that step is the next legal step for the session's player and marks the round busy.
+// A spin opens a new round and needs an unused bet ID and no unsettled round; it returns a copy of
+// the game state the player's last round ended with, zero when there is none. Any other step
+// returns a copy of the game state the round's previous step left, which the step is played from.
+func (s *RoundStore) Begin(claims session.Claims, client clientInfo, betID string, step RoundStep) (GameState, error) {
+	if betID == "" {
+		return GameState{}, apierror.New(apierror.InvalidRequest, "bet_id is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player := playerKey(claims.ClientID, claims.PlayerID)
+	if openBet, ok := s.open[player]; ok {
+		round := s.rounds[roundKey(player, openBet)]
+		details := map[string]interface{}{"betId": openBet, "phase": round.Phase, "nextStep": round.NextStep}
+		switch {
+		case round.busy:
+			return GameState{}, apierror.New(apierror.RoundBusy, "").WithDetails(details)
+		case step == StepSpin:
+			return GameState{}, apierror.New(apierror.RoundInProgress, fmt.Sprintf("Round %s is not finished, continue with %s", openBet, round.NextStep)).WithDetails(details)
+		case betID != openBet:
+			return GameState{}, apierror.New(apierror.StepOutOfOrder, fmt.Sprintf("bet_id %s is not the round in progress (%s)", betID, openBet)).WithDetails(details)
+		case step != round.NextStep:
+			return GameState{}, apierror.New(apierror.StepOutOfOrder, fmt.Sprintf("Round %s expects %s, not %s", openBet, round.NextStep, step)).WithDetails(details)
+		}
+		round.busy = true
+		return cloneGameState(round.GameState), nil
+	}
+
+	if step != StepSpin {
+		return GameState{}, apierror.New(apierror.StepOutOfOrder, fmt.Sprintf("No round in progress, %s needs a spin first", step)).
+			WithDetails(map[string]interface{}{"betId": betID, "nextStep": StepSpin})
+	}
+	if _, used := s.rounds[roundKey(player, betID)]; used {
+		return GameState{}, apierror.New(apierror.DuplicateBet, fmt.Sprintf("bet_id %s has already been played", betID))
+	}
+
+	now := s.now().Unix()
+	s.rounds[roundKey(player, betID)] = &storedRound{
+		Round:   Round{BetID: betID, Phase: PhaseSpinning, StartedAt: now, UpdatedAt: now},
+		busy:    true,
+		session: claims,
+		client:  client,
+	}
+	s.open[player] = betID
+	return cloneGameState(s.last[player]), nil
+}
+
+// End records the outcome of a step started with Begin. A failed step leaves the round as it was;
+// a failed spin discards the round, since nothing was played.
+func (s *RoundStore) End(clientID, playerID, betID string, gameState GameState, stepErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	round, ok := s.rounds[roundKey(player, betID)]
+	if !ok {
+		return
+	}
+	round.busy = false
+
+	if stepErr != nil {
+		if round.Phase == PhaseSpinning {
+			delete(s.rounds, roundKey(player, betID))
+			delete(s.open, player)
+		}
+		return
+	}
+
+	now := s.now()
+	round.GameState = gameState
+	round.Phase = phaseAfter(gameState)
+	round.NextStep = nextStep(round.Phase)
+	round.Steps++
+	round.UpdatedAt = now.Unix()
+	if round.Phase == PhaseSettled {
+		delete(s.open, player)
+		s.last[player] = gameState
+	}
+	s.sweep(now)
+}
+
+// Open returns the player's unsettled round, if any
+func (s *RoundStore) Open(clientID, playerID string) (Round, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	betID, ok := s.open[player]
+	if !ok {
+		return Round{}, false
+	}
+	return s.rounds[roundKey(player, betID)].Round, true
+}
+
+// unfinishedRound is an unsettled round with the session needed to finish it on the server
+type unfinishedRound struct {
+	Round
+	session session.Claims
+	client  clientInfo
+}
+
+// unfinished returns the player's unsettled round unless a step of it is being played
+func (s *RoundStore) unfinished(clientID, playerID string) (unfinishedRound, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	player := playerKey(clientID, playerID)
+	betID, ok := s.open[player]
+	if !ok {
+		return unfinishedRound{}, false
+	}
+	round := s.rounds[roundKey(player, betID)]
+	if round.busy {
+		return unfinishedRound{}, false
+	}
+	return unfinishedRound{Round: round.Round, session: round.session, client: round.client}, true
+}
+
+// expired returns the unsettled rounds last played before the given time
+func (s *RoundStore) expired(before time.Time) []unfinishedRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rounds []unfinishedRound
+	for player, betID := range s.open {
+		round := s.rounds[roundKey(player, betID)]
+		if !round.busy && round.UpdatedAt < before.Unix() {
+			rounds = append(rounds, unfinishedRound{Round: round.Round, session: round.session, client: round.client})
+		}
+	}
+	return rounds
+}
+
+// addRecovered keeps a round the server settled until it is reported at the player's next launch
+func (s *RoundStore) addRecovered(clientID, playerID string, recovered RecoveredRound) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	player := playerKey(clientID, playerID)
+	s.recovered[player] = append(s.recovered[player], recovered)
+}
+
+// takeRecovered returns and forgets the rounds the server settled for the player
+func (s *RoundStore) takeRecovered(clientID, playerID string) []RecoveredRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	player := playerKey(clientID, playerID)
+	recovered := s.recovered[player]
+	delete(s.recovered, player)
+	return recovered
+}
+
+// sweep forgets settled and unreported recovered rounds past retention, at most every few minutes; the caller holds s.mu
+func (s *RoundStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < 5*time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	cutoff := now.Add(-settledRoundRetention).Unix()
+	for key, round := range s.rounds {
+		if round.Phase == PhaseSettled && round.UpdatedAt < cutoff {
+			delete(s.rounds, key)
+		}
+	}
+	for player, recovered := range s.recovered {
+		if recovered[len(recovered)-1].SettledAt < cutoff {
+			delete(s.recovered, player)
+		}
+	}
+}
+
+// phaseAfter derives the round phase from the game state a step returned.
+// Stage-cleared symbols are processed before any remaining connections are cascaded.
+func phaseAfter(gameState GameState) RoundPhase {
+	switch {
+	case gameState.MaxWinReached:
+		return PhaseSettled
+	case len(gameState.StageClearedSymbols) > 0:
+		return PhaseStageClearPending
+	case gameState.Cascading:
+		return PhaseCascading
+	default:
+		return PhaseSettled
+	}
+}
+
+// nextStep returns the only step a round in phase accepts
+func nextStep(phase RoundPhase) RoundStep {
+	switch phase {
+	case PhaseStageClearPending:
+		return StepProcessStageCleared
+	case PhaseCascading:
+		return StepCascade
+	default:
+		return ""
+	}
+}
+
+// beginStep starts a step of the session player's round. Steps after the spin are played from
+// the server's copy of the round's game state, which replaces the gameState the client sent;
+// a spin starts from the state the player's last round ended with (see spinState).
+// It is a no-op when rounds are not tracked.
+func (rg *RouteGroup) beginStep(env requestEnv, client clientInfo, betID string, step RoundStep, gameState *GameState) error {
+	if rg.Rounds == nil {
+		return nil
+	}
+	stored, err := rg.Rounds.Begin(env.Session, client, betID, step)
+	if err != nil {
+		log.Printf("Rejected %s for bet %s of player %s: %v", step, betID, env.Session.PlayerID, err)
+		return err
+	}
+	if step != StepSpin {
+		*gameState = stored
+		return nil
+	}
+	*gameState = spinState(stored, *gameState, env.Currency.Code)
+	return nil
+}
+
+// spinState returns the game state a spin starts from, given the state the player's last round
+// ended with: its level, stage progress, game mode and free spins, with the cascade ladder reset.
+// Only the bet comes from the client. Free spins keep the bet that awarded them and continue the
+// round's running win; they can only be played in the currency they were awarded in, and a spin
+// in another currency starts a base round instead. A player without a last round starts a new game.
+func spinState(last, client GameState, currency string) GameState {
+	gameState := last
+	if gameState.CurrentLevel == 0 {
+		gameState = InitializeGameState()
+	}
+	// Every spin starts at the bottom of the cascade ladder
+	gameState.CascadeLevel, gameState.CascadeCount = 0, 0
+	if gameState.GameMode == "freeSpins" && gameState.Bet.Currency == currency {
+		return gameState
+	}
+	if gameState.GameMode == "freeSpins" {
+		gameState.GameMode = "base"
+		gameState.FreeSpins.Remaining, gameState.FreeSpins.TotalAwarded, gameState.FreeSpins.Multiplier = 0, 0, 1.0
+	}
+	gameState.Bet = client.Bet
+	gameState.RoundWin, gameState.MaxWinReached = 0, false
+	return gameState
+}
+
+// endStep records the outcome of a step started with beginStep
+func (rg *RouteGroup) endStep(env requestEnv, betID string, gameState GameState, err error) {
+	if rg.Rounds != nil {
+		rg.Rounds.End(env.Session.ClientID, env.Session.PlayerID, betID, gameState, err)
+	}
+}
+
+// RoundHandler handles the /round/birdsparty endpoint
+// Returns the player's unfinished round so a client that crashed mid-round can resume it
+// with the returned game state and next step
+func (rg *RouteGroup) RoundHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+
+	resp := RoundResponse{Status: "success", Message: ""}
+	if rg.Rounds != nil {
+		if round, ok := rg.Rounds.Open(env.Session.ClientID, env.Session.PlayerID); ok {
+			resp.Round = &round
+		}
+	}
+	return c.JSON(resp)
+}
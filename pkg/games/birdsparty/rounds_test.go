@@ -0,0 +1,187 @@
+package birdsparty
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
+)
+
+var testClaims = session.Claims{SessionID: "session_1", ClientID: "operator_test", GameID: GameID, PlayerID: "player_1", Currency: "USD"}
+
+// Game states a step can leave a round in
+var (
+	stageClearPendingState = GameState{StageClearedSymbols: []StageClearedSymbol{{Symbol: SymbolOrangeSlice}}, Cascading: true}
+	cascadingState         = GameState{Cascading: true, CascadeCount: 1}
+	settledState           = GameState{}
+	maxWinState            = GameState{MaxWinReached: true, StageClearedSymbols: []StageClearedSymbol{{Symbol: SymbolOrangeSlice}}, Cascading: true}
+)
+
+func TestPhaseAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		state GameState
+		phase RoundPhase
+		next  RoundStep
+	}{
+		{"stage-cleared symbols come before cascades", stageClearPendingState, PhaseStageClearPending, StepProcessStageCleared},
+		{"connections left", cascadingState, PhaseCascading, StepCascade},
+		{"nothing left", settledState, PhaseSettled, ""},
+		{"max win ends the round", maxWinState, PhaseSettled, ""},
+	}
+	for _, tt := range tests {
+		phase := phaseAfter(tt.state)
+		if phase != tt.phase || nextStep(phase) != tt.next {
+			t.Errorf("%s: phase %s, next step %q, want %s, %q", tt.name, phase, nextStep(phase), tt.phase, tt.next)
+		}
+	}
+}
+
+// startRound returns a store holding round bet_1 of testClaims' player, left in the given state
+func startRound(t *testing.T, state GameState) *RoundStore {
+	t.Helper()
+	s := NewRoundStore()
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepSpin); err != nil {
+		t.Fatal(err)
+	}
+	s.End(testClaims.ClientID, testClaims.PlayerID, "bet_1", state, nil)
+	return s
+}
+
+func TestRoundStepOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *GameState // State the round bet_1 was left in; nil means no round was played
+		betID string
+		step  RoundStep
+		want  apierror.Code // Empty when the step is allowed
+	}{
+		{"first spin", nil, "bet_1", StepSpin, ""},
+		{"cascade before any spin", nil, "bet_1", StepCascade, apierror.StepOutOfOrder},
+		{"stage clear before any spin", nil, "bet_1", StepProcessStageCleared, apierror.StepOutOfOrder},
+
+		{"stage clear when pending", &stageClearPendingState, "bet_1", StepProcessStageCleared, ""},
+		{"cascade before the pending stage clear", &stageClearPendingState, "bet_1", StepCascade, apierror.StepOutOfOrder},
+		{"spin before the pending stage clear", &stageClearPendingState, "bet_2", StepSpin, apierror.RoundInProgress},
+		{"stage clear for another bet", &stageClearPendingState, "bet_2", StepProcessStageCleared, apierror.StepOutOfOrder},
+
+		{"cascade when cascading", &cascadingState, "bet_1", StepCascade, ""},
+		{"stage clear when cascading", &cascadingState, "bet_1", StepProcessStageCleared, apierror.StepOutOfOrder},
+		{"spin before the pending cascade", &cascadingState, "bet_2", StepSpin, apierror.RoundInProgress},
+		{"cascade for another bet", &cascadingState, "bet_2", StepCascade, apierror.StepOutOfOrder},
+
+		{"new spin after settling", &settledState, "bet_2", StepSpin, ""},
+		{"repeated bet ID", &settledState, "bet_1", StepSpin, apierror.DuplicateBet},
+		{"cascade after settling", &settledState, "bet_1", StepCascade, apierror.StepOutOfOrder},
+		{"cascade after the max win", &maxWinState, "bet_1", StepCascade, apierror.StepOutOfOrder},
+		{"repeated bet ID after the max win", &maxWinState, "bet_1", StepSpin, apierror.DuplicateBet},
+	}
+	for _, tt := range tests {
+		s := NewRoundStore()
+		if tt.state != nil {
+			s = startRound(t, *tt.state)
+		}
+		_, err := s.Begin(testClaims, clientInfo{}, tt.betID, tt.step)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %s %s returned %v, want it allowed", tt.name, tt.step, tt.betID, err)
+		case tt.want != "" && !apierror.Is(err, tt.want):
+			t.Errorf("%s: %s %s returned %v, want %s", tt.name, tt.step, tt.betID, err, tt.want)
+		}
+	}
+}
+
+func TestRoundBusy(t *testing.T) {
+	s := startRound(t, cascadingState)
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepCascade); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepCascade); !apierror.Is(err, apierror.RoundBusy) {
+		t.Errorf("second cascade while the first is played returned %v, want %s", err, apierror.RoundBusy)
+	}
+}
+
+func TestResumeRound(t *testing.T) {
+	s := startRound(t, cascadingState)
+
+	round, ok := s.Open(testClaims.ClientID, testClaims.PlayerID)
+	if !ok || round.BetID != "bet_1" || round.Phase != PhaseCascading || round.NextStep != StepCascade || round.Steps != 1 {
+		t.Fatalf("open round = %+v, %v, want bet_1 cascading", round, ok)
+	}
+
+	// A new session of the same player resumes the round from the server's copy of its state
+	resumed := testClaims
+	resumed.SessionID = "session_2"
+	gs, err := s.Begin(resumed, clientInfo{}, "bet_1", StepCascade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gs.Cascading || gs.CascadeCount != 1 {
+		t.Errorf("resumed from %+v, want the stored cascading state", gs)
+	}
+	s.End(resumed.ClientID, resumed.PlayerID, "bet_1", settledState, nil)
+	if _, ok := s.Open(testClaims.ClientID, testClaims.PlayerID); ok {
+		t.Error("round still open after it settled")
+	}
+}
+
+func TestFailedSteps(t *testing.T) {
+	// A failed spin played nothing, so its bet ID can be spun again
+	s := NewRoundStore()
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepSpin); err != nil {
+		t.Fatal(err)
+	}
+	s.End(testClaims.ClientID, testClaims.PlayerID, "bet_1", GameState{}, errors.New("rng unavailable"))
+	if _, ok := s.Open(testClaims.ClientID, testClaims.PlayerID); ok {
+		t.Error("failed spin left a round open")
+	}
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepSpin); err != nil {
+		t.Errorf("spin again after a failed spin returned %v", err)
+	}
+
+	// A failed cascade leaves the round to be retried
+	s = startRound(t, cascadingState)
+	if _, err := s.Begin(testClaims, clientInfo{}, "bet_1", StepCascade); err != nil {
+		t.Fatal(err)
+	}
+	s.End(testClaims.ClientID, testClaims.PlayerID, "bet_1", settledState, errors.New("rng unavailable"))
+	if round, ok := s.Open(testClaims.ClientID, testClaims.PlayerID); !ok || round.NextStep != StepCascade || round.Steps != 1 {
+		t.Errorf("round after a failed cascade = %+v, %v, want it still waiting for the cascade", round, ok)
+	}
+}
+
+func TestSpinState(t *testing.T) {
+	client := newTestGame(0.5)
+	client.GameMode, client.RoundWin, client.CurrentLevel = "freeSpins", 99, Level3
+
+	freeSpins := newTestGame(0.1)
+	freeSpins.Bet.Currency = "USD"
+	freeSpins.CurrentLevel, freeSpins.StageProgress = Level2, 4
+	freeSpins.GameMode = "freeSpins"
+	freeSpins.FreeSpins.Remaining, freeSpins.FreeSpins.TotalAwarded, freeSpins.FreeSpins.Multiplier = 3, FreeSpinsAwarded, 2
+	freeSpins.RoundWin, freeSpins.CascadeLevel = 1.2, 4
+
+	tests := []struct {
+		name      string
+		last      GameState
+		currency  string
+		wantMode  string
+		wantBet   float64
+		wantWin   float64
+		wantLevel Level
+	}{
+		{"new player", GameState{}, "USD", "base", 0.5, 0, Level1},
+		{"free spins continue", freeSpins, "USD", "freeSpins", 0.1, 1.2, Level2},
+		{"free spins in another currency", freeSpins, "EUR", "base", 0.5, 0, Level2},
+	}
+	for _, tt := range tests {
+		gs := spinState(tt.last, client, tt.currency)
+		switch {
+		case gs.GameMode != tt.wantMode || gs.Bet.Amount != tt.wantBet || gs.RoundWin != tt.wantWin || gs.CurrentLevel != tt.wantLevel:
+			t.Errorf("%s: mode=%s bet=%v roundWin=%v level=%d, want %s, %v, %v, %d", tt.name, gs.GameMode, gs.Bet.Amount, gs.RoundWin, gs.CurrentLevel, tt.wantMode, tt.wantBet, tt.wantWin, tt.wantLevel)
+		case gs.CascadeLevel != 0 || gs.CascadeCount != 0:
+			t.Errorf("%s: cascade ladder at %d/%d, want it reset", tt.name, gs.CascadeLevel, gs.CascadeCount)
+		}
+	}
+}
@@ -1,43 +1,231 @@
 package birdsparty
 
 import (
-	"strings"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/audit"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/common/rng"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/session"
 	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/tenant"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 )
 
+// APIKeyHeader carries the operator's API key on operator requests
+const APIKeyHeader = "X-API-Key"
+
+// GameID identifies this game in session tokens and service calls
+const GameID = "birdsparty"
+
+// OutcomeProvider decides whether a winning grid is allowed to pay.
+// rng.Client is the production implementation; the rng package also provides
+// deterministic local implementations for testing and offline demos.
+type OutcomeProvider interface {
+	GetOutcome(clientID, gameID, playerID, betID string, rtp, payoutMultiplier, betAmount float64, currency, ipAddress, userAgent string) (rng.Response, error)
+}
+
+// SettingsProvider supplies the operator's game settings for a player.
+// settings.CachedClient is the production implementation; settings.StaticProvider serves fixed values.
+type SettingsProvider interface {
+	GetSettings(clientID, gameID, playerID string) (settings.Settings, error)
+}
+
+// Environment holds the providers backing one tenant environment
+type Environment struct {
+	RNG      OutcomeProvider
+	Settings SettingsProvider
+}
+
 // RouteGroup holds the dependencies for the handlers
 type RouteGroup struct {
-	RNGProd      *rng.Client
-	SettingsProd *settings.Client
-	RNGTest      *rng.Client
-	SettingsTest *settings.Client
+	Tenants      *tenant.Registry
+	Environments map[string]Environment
+
+	// Authenticate runs before every operator endpoint (HMAC request signing); nil disables it
+	Authenticate fiber.Handler
+
+	// Sessions mints player session tokens at launch and verifies them on game endpoints
+	Sessions *session.Issuer
+	// LaunchURL is the game client URL the session token is appended to; empty omits launchUrl
+	LaunchURL string
+
+	// MaxWinMultiplier is the default round win cap (as a bet multiple) for operators that set none
+	MaxWinMultiplier float64
+
+	// Audit receives one record per game step; nil disables auditing
+	Audit *audit.Logger
+
+	// WebSocketKeepAlive is the interval between keep-alives pushed on WebSocket connections
+	WebSocketKeepAlive time.Duration
+
+	// AutoplayMaxRounds caps the rounds of a single autoplay request; 0 means no cap
+	AutoplayMaxRounds int
+
+	// Responsible enforces the operators' responsible-gaming policies; nil disables enforcement
+	Responsible *responsible.Tracker
+
+	// Rounds enforces the order of each round's steps, keeps unfinished rounds for resuming and holds
+	// the game state every step after the spin is played from. NewRouteGroup creates it; nil (as in
+	// replays) plays steps from the game state they are given.
+	Rounds *RoundStore
+
+	// RoundRecovery is how an unfinished round is handled at the player's next launch: RecoveryResume or RecoveryComplete
+	RoundRecovery string
+
+	// ReelStrips holds reel strips by math variant; tenants whose variant has none draw from the symbol weight tables
+	ReelStrips map[string]StripSet
+	// Paytable prices connections on every level; nil uses DefaultPaytable
+	Paytable *Paytable
+	// Jackpots holds the progressive jackpot pools bets contribute to; nil disables jackpots
+	Jackpots *Jackpots
+	// Tournaments scores rounds in running tournaments; nil disables tournaments
+	Tournaments *Tournaments
+	// FreeRounds holds the promotional free rounds operators grant players; nil disables free rounds
+	FreeRounds *FreeRounds
+	// Missions tracks the players' progress on missions from the events of their steps; nil disables missions
+	Missions *Missions
+
+	// RoundSettleTimeout is how long a round may stay unfinished before the server completes it; 0 disables it
+	RoundSettleTimeout time.Duration
 }
 
 // NewRouteGroup creates a new RouteGroup
-func NewRouteGroup(rngProd *rng.Client, settingsProd *settings.Client, rngTest *rng.Client, settingsTest *settings.Client) *RouteGroup {
+func NewRouteGroup(tenants *tenant.Registry, environments map[string]Environment, sessions *session.Issuer) *RouteGroup {
 	return &RouteGroup{
-		RNGProd:      rngProd,
-		SettingsProd: settingsProd,
-		RNGTest:      rngTest,
-		SettingsTest: settingsTest,
+		Tenants:      tenants,
+		Environments: environments,
+		Sessions:     sessions,
+		Rounds:       NewRoundStore(),
+	}
+}
+
+// requestEnv is everything resolved from the session and tenant registry for one request
+type requestEnv struct {
+	Session  session.Claims
+	Tenant   tenant.Tenant
+	Currency money.Currency
+	RNG      OutcomeProvider
+	Settings SettingsProvider
+}
+
+// maxBet returns the tighter of the session and tenant maximum bets; 0 means no limit
+func (e requestEnv) maxBet() float64 {
+	maxBet := e.Session.MaxBet
+	if e.Tenant.Limits.MaxBet > 0 && (maxBet == 0 || e.Tenant.Limits.MaxBet < maxBet) {
+		maxBet = e.Tenant.Limits.MaxBet
+	}
+	return maxBet
+}
+
+var (
+	// errClientMismatch is returned when the body's client_id differs from the client that signed the request
+	errClientMismatch = errors.New("client_id does not match the authenticated client")
+	// errNoSession is returned when a game handler runs without a verified session
+	errNoSession = errors.New("no session on request")
+	// errUnsupportedCurrency is returned when the session's currency is not in the money table
+	errUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// Helper to select the correct clients per request.
+// Identity and environment come only from the verified session token, never from the body or headers.
+func (rg *RouteGroup) getClientsForRequest(c *fiber.Ctx) (requestEnv, error) {
+	claims, ok := session.FromContext(c)
+	if !ok {
+		return requestEnv{}, errNoSession
 	}
+	return rg.resolveSession(claims)
 }
 
-// Helper to select the correct clients per request
-func (rg *RouteGroup) getClientsForRequest(c *fiber.Ctx) (*rng.Client, *settings.Client) {
-	origin := c.Get("Origin")
-	if len(origin) > 0 && (strings.Contains(strings.ToLower(origin), "test")) {
-		return rg.RNGTest, rg.SettingsTest
+// resolveSession resolves the tenant, currency and providers for verified session claims
+func (rg *RouteGroup) resolveSession(claims session.Claims) (requestEnv, error) {
+	t, ok := rg.Tenants.Lookup(claims.ClientID)
+	if !ok {
+		return requestEnv{}, tenant.ErrUnknownTenant
+	}
+	cur, ok := money.Lookup(claims.Currency)
+	if !ok {
+		return requestEnv{}, fmt.Errorf("%w %q", errUnsupportedCurrency, claims.Currency)
+	}
+	env, ok := rg.Environments[claims.Environment]
+	if !ok {
+		return requestEnv{}, fmt.Errorf("no providers configured for environment %q", claims.Environment)
+	}
+	return requestEnv{Session: claims, Tenant: t, Currency: cur, RNG: env.RNG, Settings: env.Settings}, nil
+}
+
+// rejectTenant writes the response for a request whose tenant could not be resolved
+func rejectTenant(c *fiber.Ctx, clientID string, err error) error {
+	log.Printf("Tenant resolution failed for client_id=%s: %v", clientID, err)
+	switch {
+	case errors.Is(err, errNoSession):
+		return apierror.Write(c, apierror.Wrap(apierror.SessionRequired, err))
+	case errors.Is(err, tenant.ErrUnknownTenant), errors.Is(err, errClientMismatch):
+		return apierror.Write(c, apierror.Wrap(apierror.UnknownClient, err))
+	case errors.Is(err, errUnsupportedCurrency):
+		return apierror.Write(c, apierror.Wrap(apierror.UnsupportedCurrency, err))
+	case errors.Is(err, tenant.ErrInvalidAPIKey):
+		return apierror.Write(c, apierror.Wrap(apierror.InvalidAPIKey, err))
+	default:
+		return apierror.Write(c, apierror.Wrap(apierror.EnvironmentUnavailable, err))
 	}
-	return rg.RNGProd, rg.SettingsProd
+}
+
+// maxWinMultiplierFor returns the tenant's round win cap multiple, falling back to the server default
+func (rg *RouteGroup) maxWinMultiplierFor(t tenant.Tenant) float64 {
+	if t.Limits.MaxWinMultiplier > 0 {
+		return t.Limits.MaxWinMultiplier
+	}
+	return rg.MaxWinMultiplier
+}
+
+// paytable returns the paytable connections are paid with
+func (rg *RouteGroup) paytable() *Paytable {
+	if rg.Paytable != nil {
+		return rg.Paytable
+	}
+	return &DefaultPaytable
 }
 
 // Register registers the routes with the Fiber app
 func (rg *RouteGroup) Register(app *fiber.App) {
-	app.Post("/spin/birdsparty", rg.SpinHandler)
-	app.Post("/process-stage-cleared/birdsparty", rg.ProcessStageClearedHandler)
-	app.Post("/cascade/birdsparty", rg.CascadeHandler)
-}
\ No newline at end of file
+	// Operator endpoints, signed with the operator's secret
+	app.Post("/session/launch", rg.withAuth(rg.LaunchHandler)...)
+	app.Post("/responsible-gaming/players", rg.withAuth(rg.PlayerLimitsHandler)...)
+	app.Post("/responsible-gaming/deposits", rg.withAuth(rg.DepositHandler)...)
+	app.Post("/tournaments/leaderboard", rg.withAuth(rg.OperatorLeaderboardHandler)...)
+	app.Post("/free-rounds/grant", rg.withAuth(rg.FreeRoundsGrantHandler)...)
+	app.Post("/free-rounds/cancel", rg.withAuth(rg.FreeRoundsCancelHandler)...)
+	app.Post("/free-rounds/query", rg.withAuth(rg.FreeRoundsQueryHandler)...)
+
+	// Game endpoints, called by the game client with a session token
+	requireSession := session.Middleware(rg.Sessions)
+	app.Post("/spin/birdsparty", requireSession, rg.SpinHandler)
+	app.Post("/process-stage-cleared/birdsparty", requireSession, rg.ProcessStageClearedHandler)
+	app.Post("/cascade/birdsparty", requireSession, rg.CascadeHandler)
+	app.Post("/autoplay/birdsparty", requireSession, rg.AutoplayHandler)
+	app.Get("/round/birdsparty", requireSession, rg.RoundHandler)
+	app.Get("/jackpots/birdsparty", requireSession, rg.JackpotsHandler)
+	app.Get("/tournaments/birdsparty", requireSession, rg.TournamentsHandler)
+	app.Get("/tournaments/birdsparty/:id/leaderboard", requireSession, rg.LeaderboardHandler)
+	app.Get("/free-rounds/birdsparty", requireSession, rg.FreeRoundsHandler)
+	app.Get("/missions/birdsparty", requireSession, rg.MissionsHandler)
+	app.Post("/responsible-gaming/reality-check", requireSession, rg.RealityCheckHandler)
+
+	// Real-time game channel carrying the same steps as the endpoints above
+	app.Get("/ws/birdsparty", tokenFromQuery, requireSession, rg.upgradeWebSocket, websocket.New(rg.WebSocketHandler))
+}
+
+// withAuth prepends the operator authentication middleware, if configured, to a handler
+func (rg *RouteGroup) withAuth(handler fiber.Handler) []fiber.Handler {
+	if rg.Authenticate == nil {
+		return []fiber.Handler{handler}
+	}
+	return []fiber.Handler{rg.Authenticate, handler}
+}
@@ -0,0 +1,426 @@
+package birdsparty
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tournament scoring, one per tournament
+const (
+	ScoreWinMultiplier = "winMultiplier" // Largest round win as a multiple of the bet
+	ScoreStageCleared  = "stageCleared"  // Stage-cleared symbols collected
+	ScoreHighestLevel  = "highestLevel"  // Highest level reached
+)
+
+// Tournament is a time-boxed competition among the players of one operator. Rounds played
+// between Start and End score points by the tournament's Scoring.
+type Tournament struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	ClientID string            `json:"clientId"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Scoring  string            `json:"scoring"`
+	Prizes   []TournamentPrize `json:"prizes"`
+}
+
+// TournamentPrize is the prize of final ranks FromRank to ToRank; a ToRank of 0 means FromRank only
+type TournamentPrize struct {
+	FromRank int    `json:"fromRank"`
+	ToRank   int    `json:"toRank,omitempty"`
+	Prize    string `json:"prize"` // Paid by the prize hook, e.g. "500 EUR" or "50 free rounds"
+}
+
+// status describes where the tournament is at the given time
+func (t Tournament) status(at time.Time) string {
+	switch {
+	case at.Before(t.Start):
+		return "upcoming"
+	case at.Before(t.End):
+		return "running"
+	default:
+		return "ended"
+	}
+}
+
+// prizeFor returns the prize of a final rank, if any
+func (t Tournament) prizeFor(rank int) (string, bool) {
+	for _, p := range t.Prizes {
+		if rank >= p.FromRank && rank <= max(p.ToRank, p.FromRank) {
+			return p.Prize, true
+		}
+	}
+	return "", false
+}
+
+// LoadTournaments reads and validates tournament definitions from a JSON file:
+//
+//	[{"id": "spring-cup", "name": "Spring Cup", "clientId": "operator_001",
+//	  "start": "2026-04-01T00:00:00Z", "end": "2026-04-08T00:00:00Z", "scoring": "winMultiplier",
+//	  "prizes": [{"fromRank": 1, "prize": "500 EUR"}, {"fromRank": 2, "toRank": 10, "prize": "50 EUR"}]}, ...]
+func LoadTournaments(path string) ([]Tournament, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading tournaments: %w", err)
+	}
+	var tournaments []Tournament
+	if err := json.Unmarshal(data, &tournaments); err != nil {
+		return nil, fmt.Errorf("parsing tournaments %s: %w", path, err)
+	}
+	if err := ValidateTournaments(tournaments); err != nil {
+		return nil, err
+	}
+	return tournaments, nil
+}
+
+// ValidateTournaments checks that tournaments have unique IDs, an operator, a window that ends
+// after it starts, a known scoring and prizes for distinct ranks
+func ValidateTournaments(tournaments []Tournament) error {
+	ids := make(map[string]bool)
+	for i, t := range tournaments {
+		switch {
+		case t.ID == "":
+			return fmt.Errorf("tournament %d has no id", i)
+		case ids[t.ID]:
+			return fmt.Errorf("tournament %q is defined twice", t.ID)
+		case t.ClientID == "":
+			return fmt.Errorf("tournament %q has no clientId", t.ID)
+		case !t.End.After(t.Start):
+			return fmt.Errorf("tournament %q ends before it starts", t.ID)
+		}
+		ids[t.ID] = true
+
+		switch t.Scoring {
+		case ScoreWinMultiplier, ScoreStageCleared, ScoreHighestLevel:
+		default:
+			return fmt.Errorf("tournament %q has unknown scoring %q", t.ID, t.Scoring)
+		}
+
+		ranks := make(map[int]bool)
+		for j, p := range t.Prizes {
+			if p.FromRank < 1 || (p.ToRank != 0 && p.ToRank < p.FromRank) {
+				return fmt.Errorf("tournament %q prize %d has invalid ranks %d-%d", t.ID, j, p.FromRank, p.ToRank)
+			}
+			for rank := p.FromRank; rank <= max(p.ToRank, p.FromRank); rank++ {
+				if ranks[rank] {
+					return fmt.Errorf("tournament %q has two prizes for rank %d", t.ID, rank)
+				}
+				ranks[rank] = true
+			}
+		}
+	}
+	return nil
+}
+
+// PrizeAward is the prize a player won with their final rank
+type PrizeAward struct {
+	Rank     int
+	PlayerID string
+	Score    float64
+	Prize    string
+}
+
+// PrizeHook delivers the prizes of a tournament that has ended, e.g. through the operator's
+// wallet. A failed delivery is retried with the same awards.
+type PrizeHook interface {
+	AwardPrizes(t Tournament, awards []PrizeAward) error
+}
+
+// LogPrizes is a PrizeHook that logs the awards for the operator to pay out
+type LogPrizes struct{}
+
+// AwardPrizes logs every award
+func (LogPrizes) AwardPrizes(t Tournament, awards []PrizeAward) error {
+	for _, a := range awards {
+		log.Printf("Tournament %s (%s): rank %d player %s score %.2f wins %s", t.ID, t.ClientID, a.Rank, a.PlayerID, a.Score, a.Prize)
+	}
+	return nil
+}
+
+// tournamentEntry is one player's standing in a tournament
+type tournamentEntry struct {
+	playerID  string
+	score     float64
+	reachedAt time.Time // When the score was reached
+	rounds    int
+}
+
+// Tournaments scores rounds in running tournaments and settles their prizes once they end.
+// State is held in memory, so it is per instance and does not survive a restart.
+type Tournaments struct {
+	mu          sync.Mutex
+	tournaments []Tournament
+	entries     map[string]map[string]*tournamentEntry // By tournament ID and player ID
+	settled     map[string]bool                        // Tournaments whose prizes were delivered
+	hook        PrizeHook
+	now         func() time.Time
+}
+
+// NewTournaments creates tournaments whose prizes are delivered through hook
+func NewTournaments(tournaments []Tournament, hook PrizeHook) *Tournaments {
+	return &Tournaments{
+		tournaments: tournaments,
+		entries:     make(map[string]map[string]*tournamentEntry),
+		settled:     make(map[string]bool),
+		hook:        hook,
+		now:         time.Now,
+	}
+}
+
+// tournamentPlay is what one game step scores
+type tournamentPlay struct {
+	newRound      bool    // The step is a paid spin
+	winMultiplier float64 // Round win so far as a multiple of the bet
+	stageCleared  int     // Stage-cleared symbols collected by the step
+	level         Level   // Level after the step
+}
+
+// record scores a step of the player in every running tournament of the operator
+func (s *Tournaments) record(clientID, playerID string, play tournamentPlay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, t := range s.tournaments {
+		if t.ClientID != clientID || t.status(now) != "running" {
+			continue
+		}
+		players, ok := s.entries[t.ID]
+		if !ok {
+			players = make(map[string]*tournamentEntry)
+			s.entries[t.ID] = players
+		}
+		entry, ok := players[playerID]
+		if !ok {
+			entry = &tournamentEntry{playerID: playerID, reachedAt: now}
+			players[playerID] = entry
+		}
+
+		if play.newRound {
+			entry.rounds++
+		}
+		score := entry.score
+		switch t.Scoring {
+		case ScoreWinMultiplier:
+			score = max(score, play.winMultiplier)
+		case ScoreStageCleared:
+			score += float64(play.stageCleared)
+		case ScoreHighestLevel:
+			score = max(score, float64(play.level))
+		}
+		if score > entry.score {
+			entry.score = score
+			entry.reachedAt = now
+		}
+	}
+}
+
+// standings ranks a tournament's players: the higher score first, then whoever reached it first,
+// then by player ID. The caller holds s.mu.
+func (s *Tournaments) standings(id string) []LeaderboardEntry {
+	entries := make([]*tournamentEntry, 0, len(s.entries[id]))
+	for _, entry := range s.entries[id] {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *tournamentEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			a.reachedAt.Compare(b.reachedAt),
+			cmp.Compare(a.playerID, b.playerID),
+		)
+	})
+
+	standings := make([]LeaderboardEntry, len(entries))
+	for i, entry := range entries {
+		standings[i] = LeaderboardEntry{Rank: i + 1, PlayerID: entry.playerID, Score: entry.score, Rounds: entry.rounds}
+	}
+	return standings
+}
+
+// lookup returns the operator's tournament with the given ID; the caller holds s.mu
+func (s *Tournaments) lookup(clientID, id string) (Tournament, bool) {
+	for _, t := range s.tournaments {
+		if t.ID == id && t.ClientID == clientID {
+			return t, true
+		}
+	}
+	return Tournament{}, false
+}
+
+// info describes a tournament with the player's standing, if they have one; the caller holds s.mu
+func (s *Tournaments) info(t Tournament, standings []LeaderboardEntry, playerID string) TournamentInfo {
+	info := TournamentInfo{
+		ID:      t.ID,
+		Name:    t.Name,
+		Scoring: t.Scoring,
+		Status:  t.status(s.now()),
+		Start:   t.Start.Unix(),
+		End:     t.End.Unix(),
+		Prizes:  t.Prizes,
+	}
+	for i := range standings {
+		if standings[i].PlayerID == playerID {
+			info.Player = &standings[i]
+			break
+		}
+	}
+	return info
+}
+
+// List returns the operator's tournaments with the player's standing in each
+func (s *Tournaments) List(clientID, playerID string) []TournamentInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []TournamentInfo{}
+	for _, t := range s.tournaments {
+		if t.ClientID == clientID {
+			list = append(list, s.info(t, s.standings(t.ID), playerID))
+		}
+	}
+	return list
+}
+
+// Leaderboard returns the operator's tournament with the player's standing and its top limit
+// standings, or all of them when limit is 0
+func (s *Tournaments) Leaderboard(clientID, id, playerID string, limit int) (TournamentInfo, []LeaderboardEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.lookup(clientID, id)
+	if !ok {
+		return TournamentInfo{}, nil, false
+	}
+	standings := s.standings(id)
+	info := s.info(t, standings, playerID)
+	if limit > 0 && len(standings) > limit {
+		standings = standings[:limit]
+	}
+	return info, standings, true
+}
+
+// SettleEnded delivers the prizes of every tournament that has ended through the prize hook.
+// Each tournament is settled once; a delivery that fails is retried on the next call.
+func (s *Tournaments) SettleEnded() {
+	s.mu.Lock()
+	now := s.now()
+	type pending struct {
+		tournament Tournament
+		awards     []PrizeAward
+	}
+	var settle []pending
+	for _, t := range s.tournaments {
+		if s.settled[t.ID] || t.status(now) != "ended" {
+			continue
+		}
+		var awards []PrizeAward
+		for _, standing := range s.standings(t.ID) {
+			if prize, ok := t.prizeFor(standing.Rank); ok {
+				awards = append(awards, PrizeAward{Rank: standing.Rank, PlayerID: standing.PlayerID, Score: standing.Score, Prize: prize})
+			}
+		}
+		settle = append(settle, pending{t, awards})
+	}
+	s.mu.Unlock()
+
+	// The hook may call out to the operator, so it runs without the lock
+	for _, p := range settle {
+		if err := s.hook.AwardPrizes(p.tournament, p.awards); err != nil {
+			log.Printf("Failed to award the prizes of tournament %s: %v", p.tournament.ID, err)
+			continue
+		}
+		s.mu.Lock()
+		s.settled[p.tournament.ID] = true
+		s.mu.Unlock()
+		log.Printf("Tournament %s settled: %d prizes awarded", p.tournament.ID, len(p.awards))
+	}
+}
+
+// RunSettlement calls SettleEnded every interval; it never returns
+func (s *Tournaments) RunSettlement(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.SettleEnded()
+	}
+}
+
+// scoreTournaments scores a game step in the player's running tournaments; it is a no-op when
+// tournaments are disabled. A paid spin (cost above 0) starts a new round.
+func (rg *RouteGroup) scoreTournaments(env requestEnv, gameState GameState, bet, cost money.Amount, stageCleared int) {
+	if rg.Tournaments == nil || bet <= 0 {
+		return
+	}
+	rg.Tournaments.record(env.Session.ClientID, env.Session.PlayerID, tournamentPlay{
+		newRound:      cost > 0,
+		winMultiplier: float64(env.Currency.RoundMajor(gameState.RoundWin)) / float64(bet),
+		stageCleared:  stageCleared,
+		level:         gameState.CurrentLevel,
+	})
+}
+
+// TournamentsHandler handles the /tournaments/birdsparty endpoint
+// Returns the operator's tournaments with the player's standing in each
+func (rg *RouteGroup) TournamentsHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+
+	resp := TournamentsResponse{Status: "success", Message: "", Tournaments: []TournamentInfo{}}
+	if rg.Tournaments != nil {
+		resp.Tournaments = rg.Tournaments.List(env.Session.ClientID, env.Session.PlayerID)
+	}
+	return c.JSON(resp)
+}
+
+// LeaderboardHandler handles the /tournaments/birdsparty/:id/leaderboard endpoint
+// Returns the top of a tournament's leaderboard (limit query parameter, default 10) and the player's standing
+func (rg *RouteGroup) LeaderboardHandler(c *fiber.Ctx) error {
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+	return rg.writeLeaderboard(c, env.Session.ClientID, c.Params("id"), env.Session.PlayerID, c.QueryInt("limit", 10))
+}
+
+// OperatorLeaderboardHandler handles the /tournaments/leaderboard endpoint
+// Called by the operator (HMAC-signed) to read a tournament's full leaderboard, or its top limit entries
+func (rg *RouteGroup) OperatorLeaderboardHandler(c *fiber.Ctx) error {
+	var req LeaderboardRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse leaderboard request body: %v", err)
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, ""))
+	}
+
+	t, err := rg.authenticateOperator(c, req.ClientID)
+	if err != nil {
+		return rejectTenant(c, req.ClientID, err)
+	}
+	return rg.writeLeaderboard(c, t.ClientID, req.TournamentID, "", req.Limit)
+}
+
+// writeLeaderboard responds with a tournament's leaderboard
+func (rg *RouteGroup) writeLeaderboard(c *fiber.Ctx, clientID, id, playerID string, limit int) error {
+	if rg.Tournaments == nil {
+		return apierror.Write(c, apierror.New(apierror.FeatureDisabled, "Tournaments are not enabled"))
+	}
+	if limit < 0 {
+		return apierror.Write(c, apierror.New(apierror.InvalidRequest, "limit must not be negative"))
+	}
+	info, standings, ok := rg.Tournaments.Leaderboard(clientID, id, playerID, limit)
+	if !ok {
+		return apierror.Write(c, apierror.New(apierror.NotFound, fmt.Sprintf("Tournament %q not found", id)))
+	}
+	return c.JSON(LeaderboardResponse{Status: "success", Message: "", Tournament: info, Leaderboard: standings})
+}
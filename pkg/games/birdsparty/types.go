@@ -1,6 +1,15 @@
 package birdsparty
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/responsible"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/settings"
+)
 
 // Symbol type
 type Symbol string
@@ -69,12 +78,15 @@ type Connection struct {
 	Positions []Position `json:"positions"`
 	Count     int        `json:"count"`
 	Payout    float64    `json:"payout"`
+	// CascadeMultiplier is the cascade ladder multiplier included in Payout
+	CascadeMultiplier float64 `json:"cascadeMultiplier"`
 }
 
 // GameState represents the current state of the game
 type GameState struct {
 	Bet struct {
 		Amount     float64 `json:"amount"`
+		Currency   string  `json:"currency"`
 		Multiplier int     `json:"multiplier"`
 	} `json:"bet"`
 	CurrentLevel  Level      `json:"currentLevel"`
@@ -91,17 +103,83 @@ type GameState struct {
 	Cascading       bool         `json:"cascading"`
 	LastConnections []Connection `json:"lastConnections"`
 	CascadeCount    int          `json:"cascadeCount"`
+	// Step on the cascade multiplier ladder, kept in the server's round state; a spin and a level change reset it to 0
+	CascadeLevel int `json:"cascadeLevel"`
 	// New field for tracking stage-cleared symbols in current spin
 	StageClearedSymbols []StageClearedSymbol `json:"stageClearedSymbols"`
+	// Round win tracking for the max win cap; a round spans a base spin and any free spins it triggers
+	RoundWin      float64 `json:"roundWin"`
+	MaxWinReached bool    `json:"maxWinReached"`
+	// Reel position the grid was drawn from, for math variants with reel strips
+	Reels *ReelState `json:"reels,omitempty"`
+}
+
+// ReelState is where the reel strips of a grid stopped; cascade refills continue from the strip above each stop
+type ReelState struct {
+	Mode  string `json:"mode"`  // Game mode whose strips the stops index
+	Stops []int  `json:"stops"` // Strip index shown in the top row of each column
+}
+
+// LaunchRequest represents the request body for the /session/launch endpoint
+type LaunchRequest struct {
+	ClientID string  `json:"client_id"`
+	GameID   string  `json:"game_id"`
+	PlayerID string  `json:"player_id"`
+	Currency string  `json:"currency"`
+	MinBet   float64 `json:"min_bet"`
+	MaxBet   float64 `json:"max_bet"`
+}
+
+// LaunchResponse represents the response body for the /session/launch endpoint
+type LaunchResponse struct {
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Token     string `json:"token"`
+	SessionID string `json:"sessionId"`
+	ExpiresAt int64  `json:"expiresAt"` // Unix seconds
+	LaunchURL string `json:"launchUrl,omitempty"`
+	// UnfinishedRound is the player's round left unfinished by a disconnect, to be resumed by the client
+	UnfinishedRound *Round `json:"unfinishedRound,omitempty"`
+	// RecoveredRounds are unfinished rounds the server completed since the player's last launch
+	RecoveredRounds []RecoveredRound `json:"recoveredRounds,omitempty"`
+}
+
+// PlayerLimitsRequest represents the request body for the /responsible-gaming/players endpoint.
+// Limits replace the player's own limits; SelfExcludedUntil, when present, sets or (with a past time) lifts self-exclusion.
+type PlayerLimitsRequest struct {
+	ClientID          string             `json:"client_id"`
+	PlayerID          string             `json:"player_id"`
+	Limits            responsible.Limits `json:"limits"`
+	SelfExcludedUntil *time.Time         `json:"self_excluded_until,omitempty"`
+}
+
+// DepositRequest represents the request body for the /responsible-gaming/deposits endpoint
+type DepositRequest struct {
+	ClientID string  `json:"client_id"`
+	PlayerID string  `json:"player_id"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// RealityCheckResponse represents the response body for the /responsible-gaming/reality-check endpoint
+type RealityCheckResponse struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Session map[string]interface{} `json:"session"`
 }
 
-// SpinRequest represents the request body for the /spin endpoint
+// SpinRequest represents the request body for the /spin endpoint.
+// ClientID, GameID and PlayerID are taken from the session token; values in the body are ignored.
 type SpinRequest struct {
 	GameState GameState `json:"gameState"`
 	ClientID  string    `json:"client_id"`
 	GameID    string    `json:"game_id"`
 	PlayerID  string    `json:"player_id"`
 	BetID     string    `json:"bet_id"`
+	// FreeRoundID plays the spin as a round of the player's free round grant, at the grant's bet
+	FreeRoundID string `json:"free_round_id,omitempty"`
+
+	freeRound bool // Set once a round of the grant has been consumed; the spin then costs nothing
 }
 
 // ProcessStageClearedRequest represents the request body for the /process-stage-cleared endpoint
@@ -130,19 +208,27 @@ type SpinResponse struct {
 	StageClearedSymbols []StageClearedSymbol `json:"stageClearedSymbols"`
 	HasStageCleared     bool                 `json:"hasStageCleared"`
 	TotalCost           float64              `json:"totalCost"`
+	MaxWinReached       bool                 `json:"maxWinReached"`
+	JackpotWins         []JackpotWin         `json:"jackpotWins,omitempty"` // Jackpot pools won by the step, paid on top of totalWin
+	Missions            []MissionProgress    `json:"missions,omitempty"`    // Missions the step progressed
+	FreeRoundID         string               `json:"freeRoundId,omitempty"` // Grant the spin was played from
+	FreeRounds          []FreeRoundGrant     `json:"freeRounds"`            // The player's remaining free round grants
 }
 
 // ProcessStageClearedResponse represents the response body for the /process-stage-cleared endpoint
 type ProcessStageClearedResponse struct {
-	Status            string       `json:"status"`
-	Message           string       `json:"message"`
-	GameState         GameState    `json:"gameState"`
-	StageClearedCount int          `json:"stageClearedCount"`
-	LevelAdvanced     bool         `json:"levelAdvanced"`
-	OldLevel          Level        `json:"oldLevel,omitempty"`
-	NewLevel          Level        `json:"newLevel,omitempty"`
-	Connections       []Connection `json:"connections"`
-	TotalCost         float64      `json:"totalCost"`
+	Status            string            `json:"status"`
+	Message           string            `json:"message"`
+	GameState         GameState         `json:"gameState"`
+	StageClearedCount int               `json:"stageClearedCount"`
+	LevelAdvanced     bool              `json:"levelAdvanced"`
+	OldLevel          Level             `json:"oldLevel,omitempty"`
+	NewLevel          Level             `json:"newLevel,omitempty"`
+	Connections       []Connection      `json:"connections"`
+	TotalCost         float64           `json:"totalCost"`
+	MaxWinReached     bool              `json:"maxWinReached"`
+	JackpotWins       []JackpotWin      `json:"jackpotWins,omitempty"` // Jackpot pools won by the step, paid on top of totalWin
+	Missions          []MissionProgress `json:"missions,omitempty"`    // Missions the step progressed
 }
 
 // CascadeResponse represents the response body for the /cascade endpoint
@@ -154,6 +240,325 @@ type CascadeResponse struct {
 	StageClearedSymbols []StageClearedSymbol `json:"stageClearedSymbols"`
 	HasStageCleared     bool                 `json:"hasStageCleared"`
 	TotalCost           float64              `json:"totalCost"`
+	MaxWinReached       bool                 `json:"maxWinReached"`
+	JackpotWins         []JackpotWin         `json:"jackpotWins,omitempty"` // Jackpot pools won by the step, paid on top of totalWin
+	Missions            []MissionProgress    `json:"missions,omitempty"`    // Missions the step progressed
+}
+
+// RoundPhase is where a round stands between endpoint calls
+type RoundPhase string
+
+// Round phases; a round is one spin and every stage-cleared step and cascade that follows it
+const (
+	PhaseSpinning          RoundPhase = "spinning"          // The spin is being played
+	PhaseStageClearPending RoundPhase = "stageClearPending" // Stage-cleared symbols are on the grid
+	PhaseCascading         RoundPhase = "cascading"         // Connections remain to be cascaded
+	PhaseSettled           RoundPhase = "settled"           // Nothing left to play
+)
+
+// RoundStep is a call that advances a round
+type RoundStep string
+
+// Round steps, one per game endpoint
+const (
+	StepSpin                RoundStep = "spin"
+	StepProcessStageCleared RoundStep = "processStageCleared"
+	StepCascade             RoundStep = "cascade"
+)
+
+// Round is the server's record of a player's round
+type Round struct {
+	BetID     string     `json:"betId"`
+	Phase     RoundPhase `json:"phase"`
+	NextStep  RoundStep  `json:"nextStep,omitempty"` // The only step accepted next; empty once settled
+	GameState GameState  `json:"gameState"`          // The state returned by the last step
+	Steps     int        `json:"steps"`
+	StartedAt int64      `json:"startedAt"` // Unix seconds
+	UpdatedAt int64      `json:"updatedAt"` // Unix seconds
+}
+
+// RecoveredRound is an unfinished round the server completed on the player's behalf
+type RecoveredRound struct {
+	BetID      string    `json:"betId"`
+	Currency   string    `json:"currency"`
+	Win        float64   `json:"win"`                  // Won by the steps the server played
+	JackpotWin float64   `json:"jackpotWin,omitempty"` // Jackpot pools those steps won, on top of win
+	Steps      int       `json:"steps"`                // Steps the server played
+	GameState  GameState `json:"gameState"`
+	SettledAt  int64     `json:"settledAt"` // Unix seconds
+}
+
+// JackpotWin is a jackpot pool won by a step, in major units of the session currency
+type JackpotWin struct {
+	Pool   string  `json:"pool"`
+	Amount float64 `json:"amount"`
+}
+
+// JackpotValue is the current value of a jackpot pool, in major units of the session currency
+type JackpotValue struct {
+	Pool   string  `json:"pool"`
+	Amount float64 `json:"amount"`
+}
+
+// JackpotsResponse represents the response body for the /jackpots endpoint
+type JackpotsResponse struct {
+	Status   string         `json:"status"`
+	Message  string         `json:"message"`
+	Currency string         `json:"currency"`
+	Jackpots []JackpotValue `json:"jackpots"`
+}
+
+// MissionProgress is a player's progress on a mission. In a step response, completed is true
+// only on the step that completes the mission, since completed missions no longer progress.
+type MissionProgress struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Event       GameEventType `json:"event"`
+	Progress    int           `json:"progress"`
+	Target      int           `json:"target"`
+	Completed   bool          `json:"completed"`
+	CompletedAt int64         `json:"completedAt,omitempty"` // Unix seconds
+}
+
+// MissionsResponse represents the response body for the /missions/birdsparty endpoint
+type MissionsResponse struct {
+	Status   string            `json:"status"`
+	Message  string            `json:"message"`
+	Missions []MissionProgress `json:"missions"`
+}
+
+// FreeRoundGrant describes free rounds granted to a player by the operator
+type FreeRoundGrant struct {
+	ID        string  `json:"id"`
+	Status    string  `json:"status"` // active, completed, expired or cancelled
+	Rounds    int     `json:"rounds"`
+	Remaining int     `json:"remaining"`
+	BetAmount float64 `json:"betAmount"`
+	Currency  string  `json:"currency"`
+	GrantedAt int64   `json:"grantedAt"` // Unix seconds
+	ExpiresAt int64   `json:"expiresAt"` // Unix seconds
+	// Win is what the grant's rounds have won, tracked apart from paid play for wagering requirements
+	Win                 float64 `json:"win"`
+	WageringMultiplier  float64 `json:"wageringMultiplier,omitempty"`
+	WageringRequirement float64 `json:"wageringRequirement,omitempty"` // Win × wageringMultiplier
+}
+
+// FreeRoundsGrantRequest represents the request body for the /free-rounds/grant endpoint.
+// GrantID is the operator's own ID for the grant; granting it again with the same terms is a no-op.
+type FreeRoundsGrantRequest struct {
+	ClientID           string    `json:"client_id"`
+	PlayerID           string    `json:"player_id"`
+	GrantID            string    `json:"grant_id"`
+	Rounds             int       `json:"rounds"`
+	BetAmount          float64   `json:"bet_amount"`
+	Currency           string    `json:"currency"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	WageringMultiplier float64   `json:"wagering_multiplier,omitempty"`
+}
+
+// FreeRoundsCancelRequest represents the request body for the /free-rounds/cancel endpoint
+type FreeRoundsCancelRequest struct {
+	ClientID string `json:"client_id"`
+	PlayerID string `json:"player_id"`
+	GrantID  string `json:"grant_id"`
+}
+
+// FreeRoundsQueryRequest represents the request body for the /free-rounds/query endpoint
+type FreeRoundsQueryRequest struct {
+	ClientID string `json:"client_id"`
+	PlayerID string `json:"player_id"`
+}
+
+// FreeRoundGrantResponse represents the response body for the /free-rounds/grant and /free-rounds/cancel endpoints
+type FreeRoundGrantResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Grant   FreeRoundGrant `json:"grant"`
+}
+
+// FreeRoundsResponse represents the response body for the free round query endpoints
+type FreeRoundsResponse struct {
+	Status     string           `json:"status"`
+	Message    string           `json:"message"`
+	FreeRounds []FreeRoundGrant `json:"freeRounds"`
+}
+
+// LeaderboardEntry is a player's standing in a tournament
+type LeaderboardEntry struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"playerId"`
+	Score    float64 `json:"score"`
+	Rounds   int     `json:"rounds"` // Paid rounds played in the tournament
+}
+
+// TournamentInfo describes a tournament to a player
+type TournamentInfo struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Scoring string            `json:"scoring"` // "winMultiplier", "stageCleared" or "highestLevel"
+	Status  string            `json:"status"`  // "upcoming", "running" or "ended"
+	Start   int64             `json:"start"`   // Unix seconds
+	End     int64             `json:"end"`     // Unix seconds
+	Prizes  []TournamentPrize `json:"prizes"`
+	Player  *LeaderboardEntry `json:"player"` // The player's standing, or null before they play
+}
+
+// TournamentsResponse represents the response body for the /tournaments/birdsparty endpoint
+type TournamentsResponse struct {
+	Status      string           `json:"status"`
+	Message     string           `json:"message"`
+	Tournaments []TournamentInfo `json:"tournaments"`
+}
+
+// LeaderboardRequest represents the request body for the operator /tournaments/leaderboard endpoint
+type LeaderboardRequest struct {
+	ClientID     string `json:"client_id"`
+	TournamentID string `json:"tournament_id"`
+	Limit        int    `json:"limit"` // Top entries to return; 0 returns all
+}
+
+// LeaderboardResponse represents the response body for the leaderboard endpoints
+type LeaderboardResponse struct {
+	Status      string             `json:"status"`
+	Message     string             `json:"message"`
+	Tournament  TournamentInfo     `json:"tournament"`
+	Leaderboard []LeaderboardEntry `json:"leaderboard"`
+}
+
+// Draw is one random number consumed by a game step: an Intn result when N is set, otherwise a Float64
+type Draw struct {
+	N     int     `json:"n,omitempty"`
+	Value float64 `json:"v"`
+}
+
+// StepReplay is everything a game step consumed, recorded in the audit trail so the step
+// can be re-executed exactly with Replay
+type StepReplay struct {
+	Step             RoundStep         `json:"step"`
+	BetID            string            `json:"betId"`
+	Currency         string            `json:"currency"`
+	Settings         settings.Settings `json:"settings"`
+	MaxWinMultiplier float64           `json:"maxWinMultiplier"`    // Server or tenant default for the win cap
+	MathVariant      string            `json:"mathVariant"`         // Selects the reel strips, if any
+	GameState        GameState         `json:"gameState"`           // As sent by the client
+	FreeRound        bool              `json:"freeRound,omitempty"` // A spin played from a free round grant, which stakes nothing
+	Outcomes         []string          `json:"outcomes"`            // RNG service outcomes, in order
+	Draws            []Draw            `json:"draws"`               // Random draws, in order
+	Grid             [][]string        `json:"grid"`                // Grid the step produced
+}
+
+// RoundResponse represents the response body for the /round/birdsparty endpoint
+type RoundResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Round   *Round `json:"round"` // The unfinished round, or null
+}
+
+// Autoplay stop reasons
+const (
+	StopCompleted           = "completed"
+	StopWinAbove            = "winAbove"
+	StopSingleWinLimit      = "singleWinLimit"
+	StopFreeSpinsTriggered  = "freeSpinsTriggered"
+	StopLevelAdvanced       = "levelAdvanced"
+	StopLossLimit           = "lossLimit"
+	StopInsufficientBalance = "insufficientBalance"
+	StopResponsibleGaming   = "responsibleGaming"
+	StopError               = "error"
+)
+
+// AutoplayStopConditions end an autoplay run early; zero values disable a condition.
+// Amounts are in the session currency.
+type AutoplayStopConditions struct {
+	WinAbove       float64 `json:"winAbove"`       // Stop after a round winning more than this
+	SingleWinLimit float64 `json:"singleWinLimit"` // Stop after a single spin, cascade or stage-cleared step wins at least this
+	OnFreeSpins    bool    `json:"onFreeSpins"`    // Stop when free spins are triggered
+	OnLevelAdvance bool    `json:"onLevelAdvance"` // Stop when the level advances
+	LossLimit      float64 `json:"lossLimit"`      // Stop once net losses for the run reach this
+}
+
+// AutoplayRequest represents the request body for the /autoplay endpoint.
+// Each round is played with bet ID "<bet_id>-<round>".
+type AutoplayRequest struct {
+	GameState      GameState              `json:"gameState"`
+	ClientID       string                 `json:"client_id"`
+	GameID         string                 `json:"game_id"`
+	PlayerID       string                 `json:"player_id"`
+	BetID          string                 `json:"bet_id"`
+	Rounds         int                    `json:"rounds"`
+	Balance        float64                `json:"balance"` // Advisory wallet balance the client reports; it only ends the run early
+	StopConditions AutoplayStopConditions `json:"stopConditions"`
+}
+
+// AutoplayRoundSummary summarizes one autoplay round: a spin and all of its stage-cleared steps and cascades
+type AutoplayRoundSummary struct {
+	Round              int      `json:"round"`
+	BetID              string   `json:"betId"`
+	Cost               float64  `json:"cost"`
+	Win                float64  `json:"win"`
+	JackpotWin         float64  `json:"jackpotWin,omitempty"` // Jackpot pools won in the round, on top of win
+	Steps              int      `json:"steps"`
+	Level              Level    `json:"level"`
+	GameMode           string   `json:"gameMode"`
+	LevelAdvanced      bool     `json:"levelAdvanced"`
+	FreeSpinsTriggered bool     `json:"freeSpinsTriggered"`
+	MaxWinReached      bool     `json:"maxWinReached"`
+	Balance            float64  `json:"balance"`
+	MissionsCompleted  []string `json:"missionsCompleted,omitempty"` // IDs of the missions the round completed
+	Unfinished         bool     `json:"unfinished,omitempty"`        // A step after the spin failed; the round is left to resume
+}
+
+// AutoplayResponse represents the response body for the /autoplay endpoint
+type AutoplayResponse struct {
+	Status        string                 `json:"status"`
+	Message       string                 `json:"message"`
+	GameState     GameState              `json:"gameState"` // State after the last round, to continue playing from
+	Rounds        []AutoplayRoundSummary `json:"rounds"`
+	RoundsPlayed  int                    `json:"roundsPlayed"`
+	StopReason    string                 `json:"stopReason"`
+	TotalCost     float64                `json:"totalCost"`
+	TotalWin      float64                `json:"totalWin"`
+	Balance       float64                `json:"balance"`
+	BalanceSource string                 `json:"balanceSource"` // Always BalanceSourceClient; the operator's wallet is authoritative
+}
+
+// BalanceSourceClient marks autoplay balances projected from the balance the client reported: the server
+// has no wallet, so the balances are advisory and never authorize a bet
+const BalanceSourceClient = "client"
+
+// WebSocket message types
+const (
+	MessageSpin                = "spin"
+	MessageCascade             = "cascade"
+	MessageProcessStageCleared = "processStageCleared"
+	MessageAutoplay            = "autoplay"
+	MessageAutoplayRound       = "autoplayRound"
+	MessageBalance             = "balance"
+	MessageKeepAlive           = "keepAlive"
+	MessageError               = "error"
+)
+
+// SocketMessage is the envelope for every message on the /ws/birdsparty channel.
+// Client messages carry a request body in Data; the server answers with the same ID and Type
+// and the matching response body, or with Type "error".
+type SocketMessage struct {
+	ID   string          `json:"id,omitempty"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BalanceUpdate is pushed after every step with the connection's running totals in the session currency.
+// The player's wallet is held by the operator; these totals are the net effect of this connection.
+type BalanceUpdate struct {
+	Currency string  `json:"currency"`
+	TotalBet float64 `json:"totalBet"`
+	TotalWin float64 `json:"totalWin"`
+	Net      float64 `json:"net"`
+}
+
+// KeepAlive is pushed periodically so clients can detect a dead connection
+type KeepAlive struct {
+	Time int64 `json:"time"` // Unix seconds
 }
 
 // ValidateLevel validates the current level
@@ -208,15 +613,15 @@ func (l Level) GetStageClearedSymbol() Symbol {
 	}
 }
 
-// BetAmountToMultiplier maps bet amounts to multipliers
-var BetAmountToMultiplier = map[float64]int{
-	0.1: 1,
-	0.2: 2,
-	0.3: 3,
-	0.5: 5,
-	1.0: 10,
-	2.0: 20,
-	2.5: 25,
+// BetMultiplier returns the bet multiplier for a bet in minor units of cur.
+// A multiplier of 1 stakes MinBet credits at the currency's denomination, so the bet
+// must be a whole multiple of that base bet.
+func BetMultiplier(bet money.Amount, cur money.Currency) (int, error) {
+	base := cur.Denomination * MinBet
+	if base <= 0 || bet <= 0 || bet%base != 0 {
+		return 0, fmt.Errorf("invalid bet amount, bets must be multiples of %s", cur.Format(base))
+	}
+	return int(bet / base), nil
 }
 
 // Symbol weights for random generation (global across all levels)
@@ -233,74 +638,121 @@ var SymbolWeights = map[Symbol]float64{
 	SymbolStrawberry:  0.0001, // 0.1%
 }
 
-// GetLevelSpecificWeights returns symbol weights for a specific level
-// Stage-cleared symbols only appear on their corresponding level
-func GetLevelSpecificWeights(level Level) map[Symbol]float64 {
-	weights := make(map[Symbol]float64)
+// SymbolWeight is the relative chance of a symbol being drawn
+type SymbolWeight struct {
+	Symbol Symbol
+	Weight float64
+}
 
-	// Base weights for all levels
-	weights[SymbolPurpleOwl] = 0.2475
-	weights[SymbolGreenOwl] = 0.2475
-	weights[SymbolYellowOwl] = 0.2475
-	weights[SymbolBlueOwl] = 0.2475
-	weights[SymbolRedOwl] = 0.2475
-	weights[SymbolFreeGame] = 0.1 // much rarer(0.002) for testing 0.1 is okay
+// SymbolWeightTable holds the symbol weights of one level and game mode. Columns overrides
+// the weights of individual reel columns (0 is the leftmost); every other column uses Weights.
+type SymbolWeightTable struct {
+	Weights []SymbolWeight
+	Columns map[int][]SymbolWeight
+}
 
-	// Add level-specific stage-cleared symbol
-	switch level {
-	case Level1:
-		weights[SymbolOrangeSlice] = 0.002 // much rarer(0.002) for testing 0.1 is okay
-	case Level2:
-		weights[SymbolHoneyPot] = 0.1 // much rarer(0.002) for testing 0.1 is okay
-	case Level3:
-		weights[SymbolStrawberry] = 0.1 // much rarer(0.002) for testing 0.1 is okay
-	}
+// birdWeights are the weights of the regular bird symbols shared by the default tables
+var birdWeights = []SymbolWeight{
+	{SymbolPurpleOwl, 0.2475},
+	{SymbolGreenOwl, 0.2475},
+	{SymbolYellowOwl, 0.2475},
+	{SymbolBlueOwl, 0.2475},
+	{SymbolRedOwl, 0.2475},
+}
 
-	return weights
+// withWeights returns birdWeights followed by the given weights
+func withWeights(weights ...SymbolWeight) []SymbolWeight {
+	return append(slices.Clone(birdWeights), weights...)
 }
 
-// Paytables for each level (payouts for Bet Multiplier = 1)
-// Only regular bird symbols have payouts, stage-cleared symbols don't pay
+// SymbolWeightTables holds the weight table of every level by game mode ("base" or "freeSpins").
+// Grids and gravity refills draw each cell from the table of the level, mode and column it is in.
+// Stage-cleared symbols only appear on their corresponding level; free_game never appears in free spins.
+var SymbolWeightTables = map[Level]map[string]SymbolWeightTable{
+	Level1: {
+		"base":      {Weights: withWeights(SymbolWeight{SymbolFreeGame, 0.1}, SymbolWeight{SymbolOrangeSlice, 0.002})}, // much rarer(0.002) for testing 0.1 is okay
+		"freeSpins": {Weights: withWeights(SymbolWeight{SymbolOrangeSlice, 0.002})},
+	},
+	Level2: {
+		"base":      {Weights: withWeights(SymbolWeight{SymbolFreeGame, 0.1}, SymbolWeight{SymbolHoneyPot, 0.1})}, // much rarer(0.002) for testing 0.1 is okay
+		"freeSpins": {Weights: withWeights(SymbolWeight{SymbolHoneyPot, 0.1})},
+	},
+	Level3: {
+		"base":      {Weights: withWeights(SymbolWeight{SymbolFreeGame, 0.1}, SymbolWeight{SymbolStrawberry, 0.1})}, // much rarer(0.002) for testing 0.1 is okay
+		"freeSpins": {Weights: withWeights(SymbolWeight{SymbolStrawberry, 0.1})},
+	},
+}
 
-// Level 1 Paytable (4x4 grid, supports 4-16 connected symbols)
-var PaytableLevel1 = map[Symbol]map[int]float64{
-	SymbolPurpleOwl: {4: 2, 5: 4, 6: 5, 7: 8, 8: 10, 9: 20, 10: 30, 11: 50, 12: 100, 13: 200, 14: 400, 15: 400, 16: 400},
-	SymbolGreenOwl:  {4: 4, 5: 5, 6: 10, 7: 20, 8: 30, 9: 50, 10: 100, 11: 250, 12: 500, 13: 750, 14: 800, 15: 800, 16: 800},
-	SymbolYellowOwl: {4: 5, 5: 10, 6: 20, 7: 40, 8: 80, 9: 160, 10: 500, 11: 1000, 12: 2000, 13: 5000, 14: 6000, 15: 6000, 16: 6000},
-	SymbolBlueOwl:   {4: 10, 5: 30, 6: 50, 7: 60, 8: 100, 9: 750, 10: 1000, 11: 10000, 12: 20000, 13: 50000, 14: 60000, 15: 60000, 16: 60000},
-	SymbolRedOwl:    {4: 20, 5: 50, 6: 100, 7: 500, 8: 1000, 9: 2000, 10: 5000, 11: 20000, 12: 50000, 13: 60000, 14: 80000, 15: 80000, 16: 80000},
+// GetSymbolWeights returns the weights for a cell of the given level, game mode and column, in a fixed order.
+// The result is a copy the caller may modify.
+func GetSymbolWeights(level Level, mode string, column int) []SymbolWeight {
+	tables, ok := SymbolWeightTables[level]
+	if !ok {
+		tables = SymbolWeightTables[Level1]
+	}
+	table, ok := tables[mode]
+	if !ok {
+		table = tables["base"]
+	}
+	if weights, ok := table.Columns[column]; ok {
+		return slices.Clone(weights)
+	}
+	return slices.Clone(table.Weights)
 }
 
-// Level 2 Paytable (5x5 grid, supports 5-25 connected symbols)
-var PaytableLevel2 = map[Symbol]map[int]float64{
-	SymbolPurpleOwl: {5: 2, 6: 4, 7: 5, 8: 8, 9: 10, 10: 20, 11: 30, 12: 50, 13: 100, 14: 200, 15: 450, 16: 450, 17: 450, 18: 450, 19: 450, 20: 450, 21: 450, 22: 450, 23: 450, 24: 450, 25: 450},
-	SymbolGreenOwl:  {5: 4, 6: 5, 7: 10, 8: 20, 9: 30, 10: 50, 11: 100, 12: 250, 13: 500, 14: 750, 15: 1000, 16: 1000, 17: 1000, 18: 1000, 19: 1000, 20: 1000, 21: 1000, 22: 1000, 23: 1000, 24: 1000, 25: 1000},
-	SymbolYellowOwl: {5: 5, 6: 10, 7: 20, 8: 40, 9: 80, 10: 160, 11: 500, 12: 1000, 13: 2000, 14: 5000, 15: 7000, 16: 7000, 17: 7000, 18: 7000, 19: 7000, 20: 7000, 21: 7000, 22: 7000, 23: 7000, 24: 7000, 25: 7000},
-	SymbolBlueOwl:   {5: 10, 6: 30, 7: 50, 8: 60, 9: 100, 10: 750, 11: 1000, 12: 10000, 13: 20000, 14: 50000, 15: 70000, 16: 70000, 17: 70000, 18: 70000, 19: 70000, 20: 70000, 21: 70000, 22: 70000, 23: 70000, 24: 70000, 25: 70000},
-	SymbolRedOwl:    {5: 20, 6: 50, 7: 100, 8: 500, 9: 1000, 10: 2000, 11: 5000, 12: 20000, 13: 50000, 14: 80000, 15: 100000, 16: 100000, 17: 100000, 18: 100000, 19: 100000, 20: 100000, 21: 100000, 22: 100000, 23: 100000, 24: 100000, 25: 100000},
+// GetLevelSpecificWeights returns the base game weights of a level's columns without an override
+func GetLevelSpecificWeights(level Level) []SymbolWeight {
+	return GetSymbolWeights(level, "base", -1)
 }
 
-// Level 3 Paytable (6x6 grid, supports 6-36 connected symbols)
-var PaytableLevel3 = map[Symbol]map[int]float64{
-	SymbolPurpleOwl: {6: 2, 7: 4, 8: 5, 9: 8, 10: 10, 11: 20, 12: 30, 13: 50, 14: 100, 15: 200, 16: 500, 17: 500, 18: 500, 19: 500, 20: 500, 21: 500, 22: 500, 23: 500, 24: 500, 25: 500, 26: 500, 27: 500, 28: 500, 29: 500, 30: 500, 31: 500, 32: 500, 33: 500, 34: 500, 35: 500, 36: 500},
-	SymbolGreenOwl:  {6: 4, 7: 5, 8: 10, 9: 20, 10: 30, 11: 50, 12: 100, 13: 250, 14: 500, 15: 750, 16: 1200, 17: 1200, 18: 1200, 19: 1200, 20: 1200, 21: 1200, 22: 1200, 23: 1200, 24: 1200, 25: 1200, 26: 1200, 27: 1200, 28: 1200, 29: 1200, 30: 1200, 31: 1200, 32: 1200, 33: 1200, 34: 1200, 35: 1200, 36: 1200},
-	SymbolYellowOwl: {6: 5, 7: 10, 8: 20, 9: 40, 10: 80, 11: 160, 12: 500, 13: 1000, 14: 2000, 15: 5000, 16: 8000, 17: 8000, 18: 8000, 19: 8000, 20: 8000, 21: 8000, 22: 8000, 23: 8000, 24: 8000, 25: 8000, 26: 8000, 27: 8000, 28: 8000, 29: 8000, 30: 8000, 31: 8000, 32: 8000, 33: 8000, 34: 8000, 35: 8000, 36: 8000},
-	SymbolBlueOwl:   {6: 10, 7: 30, 8: 50, 9: 60, 10: 100, 11: 750, 12: 1000, 13: 10000, 14: 20000, 15: 50000, 16: 80000, 17: 80000, 18: 80000, 19: 80000, 20: 80000, 21: 80000, 22: 80000, 23: 80000, 24: 80000, 25: 80000, 26: 80000, 27: 80000, 28: 80000, 29: 80000, 30: 80000, 31: 80000, 32: 80000, 33: 80000, 34: 80000, 35: 80000, 36: 80000},
-	SymbolRedOwl:    {6: 20, 7: 50, 8: 100, 9: 500, 10: 1000, 11: 2000, 12: 5000, 13: 20000, 14: 50000, 15: 100000, 16: 100000, 17: 100000, 18: 100000, 19: 100000, 20: 100000, 21: 100000, 22: 100000, 23: 100000, 24: 100000, 25: 100000, 26: 100000, 27: 100000, 28: 100000, 29: 100000, 30: 100000, 31: 100000, 32: 100000, 33: 100000, 34: 100000, 35: 100000, 36: 100000},
+// ValidateSymbolWeightTables checks that every level has a table for both game modes, that every
+// weight is positive, that stage-cleared symbols only appear on their own level, that free_game
+// does not appear in free spins and that column overrides lie on the level's grid
+func ValidateSymbolWeightTables() error {
+	for _, level := range []Level{Level1, Level2, Level3} {
+		for _, mode := range []string{"base", "freeSpins"} {
+			table, ok := SymbolWeightTables[level][mode]
+			if !ok {
+				return fmt.Errorf("level %d has no %s weight table", level, mode)
+			}
+			if err := validateSymbolWeights(table.Weights, level, mode); err != nil {
+				return fmt.Errorf("level %d %s weights: %w", level, mode, err)
+			}
+			for column, weights := range table.Columns {
+				if column < 0 || column >= level.GetGridSize() {
+					return fmt.Errorf("level %d %s weights: column %d is outside the %dx%d grid", level, mode, column, level.GetGridSize(), level.GetGridSize())
+				}
+				if err := validateSymbolWeights(weights, level, mode); err != nil {
+					return fmt.Errorf("level %d %s weights, column %d: %w", level, mode, column, err)
+				}
+			}
+		}
+	}
+	return nil
 }
 
-// GetPaytable returns the appropriate paytable for the given level
-func GetPaytable(level Level) map[Symbol]map[int]float64 {
-	switch level {
-	case Level1:
-		return PaytableLevel1
-	case Level2:
-		return PaytableLevel2
-	case Level3:
-		return PaytableLevel3
-	default:
-		return PaytableLevel1
+// validateSymbolWeights checks one list of weights
+func validateSymbolWeights(weights []SymbolWeight, level Level, mode string) error {
+	if len(weights) == 0 {
+		return fmt.Errorf("no symbols")
+	}
+	seen := make(map[Symbol]bool)
+	for _, w := range weights {
+		switch {
+		case w.Weight <= 0:
+			return fmt.Errorf("%s has weight %g, weights must be positive", w.Symbol, w.Weight)
+		case seen[w.Symbol]:
+			return fmt.Errorf("%s is listed twice", w.Symbol)
+		case IsStageClearedSymbol(w.Symbol) && w.Symbol != level.GetStageClearedSymbol():
+			return fmt.Errorf("stage-cleared symbol %s belongs to another level", w.Symbol)
+		case w.Symbol == SymbolFreeGame && mode == "freeSpins":
+			return fmt.Errorf("%s cannot appear in free spins", w.Symbol)
+		case !IsRegularBirdSymbol(w.Symbol) && !IsStageClearedSymbol(w.Symbol) && w.Symbol != SymbolFreeGame:
+			return fmt.Errorf("unknown symbol %q", w.Symbol)
+		}
+		seen[w.Symbol] = true
 	}
+	return nil
 }
 
 // IsStageClearedSymbol checks if a symbol is a stage-cleared symbol
@@ -0,0 +1,173 @@
+package birdsparty
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+)
+
+// maxCascadeCount bounds the cascade counter of a game state; no real round comes close
+const maxCascadeCount = 200
+
+// ValidateGameState checks a game state sent back by the client for internal consistency:
+// the level and grid, every symbol on it, stage progress, free spins, the connections the
+// previous step reported and the cascade counters. It returns an INVALID_GAME_STATE (or, for
+// grid size problems, GRID_MISMATCH) error giving the first inconsistency found. With a round
+// store the state is the server's own, and each cascade counts one on from the previous step.
+func ValidateGameState(gameState GameState) error {
+	level := gameState.CurrentLevel
+	if err := level.ValidateLevel(); err != nil {
+		return invalidGameState("%v", err)
+	}
+
+	gridSize := level.GetGridSize()
+	if gameState.GridSize != gridSize {
+		return apierror.New(apierror.GridMismatch, fmt.Sprintf("gridSize is %d, level %d uses %d", gameState.GridSize, level, gridSize))
+	}
+	if !ValidateGridDimensions(gameState.Grid, level) {
+		return apierror.New(apierror.GridMismatch, fmt.Sprintf("grid must be %dx%d for level %d", gridSize, gridSize, level))
+	}
+	if err := validateGridSymbols(gameState.Grid, level); err != nil {
+		return err
+	}
+
+	if gameState.StageProgress < 0 || gameState.StageProgress >= StageProgressTarget {
+		return invalidGameState("stageProgress %d is outside 0-%d", gameState.StageProgress, StageProgressTarget-1)
+	}
+	if err := validateStageClearedSymbols(gameState.StageClearedSymbols, gameState.Grid, level); err != nil {
+		return err
+	}
+	if err := validateFreeSpins(gameState); err != nil {
+		return err
+	}
+	if err := validateLastConnections(gameState.LastConnections, gameState.Grid, level); err != nil {
+		return err
+	}
+
+	if gameState.CascadeCount < 0 || gameState.CascadeCount > maxCascadeCount {
+		return invalidGameState("cascadeCount %d is outside 0-%d", gameState.CascadeCount, maxCascadeCount)
+	}
+	// The cascade count is the position in a running chain of cascades, so it is 0 once the chain ends
+	if !gameState.Cascading && gameState.CascadeCount != 0 {
+		return invalidGameState("cascadeCount is %d but no cascade is pending", gameState.CascadeCount)
+	}
+	// Every cascade of the chain climbed the ladder, so the ladder is never below the cascade count
+	if gameState.CascadeLevel < gameState.CascadeCount || gameState.CascadeLevel > maxCascadeCount {
+		return invalidGameState("cascadeLevel %d is outside %d-%d", gameState.CascadeLevel, gameState.CascadeCount, maxCascadeCount)
+	}
+	if gameState.Cascading && len(gameState.LastConnections) == 0 {
+		return invalidGameState("cascading is set but lastConnections is empty")
+	}
+	if gameState.TotalWin < 0 || gameState.RoundWin < 0 {
+		return invalidGameState("totalWin and roundWin must not be negative")
+	}
+	return nil
+}
+
+// validateGridSymbols checks that every cell holds a known symbol that can appear on the level
+func validateGridSymbols(grid [][]string, level Level) error {
+	stageSymbol := level.GetStageClearedSymbol()
+	for y, row := range grid {
+		for x, cell := range row {
+			symbol := Symbol(cell)
+			switch {
+			case IsRegularBirdSymbol(symbol), symbol == SymbolFreeGame, symbol == stageSymbol:
+			case IsStageClearedSymbol(symbol):
+				return invalidGameState("stage-cleared symbol %s at (%d,%d) belongs to another level, level %d uses %s", symbol, x, y, level, stageSymbol)
+			default:
+				return invalidGameState("unknown symbol %q at (%d,%d)", cell, x, y)
+			}
+		}
+	}
+	return nil
+}
+
+// validateStageClearedSymbols checks that the reported stage-cleared symbols are on the grid, once each
+func validateStageClearedSymbols(symbols []StageClearedSymbol, grid [][]string, level Level) error {
+	seen := make(map[Position]bool)
+	for _, s := range symbols {
+		if s.Symbol != level.GetStageClearedSymbol() {
+			return invalidGameState("stageClearedSymbols holds %s, level %d uses %s", s.Symbol, level, level.GetStageClearedSymbol())
+		}
+		if !onGrid(s.Position, grid) || grid[s.Position.Y][s.Position.X] != string(s.Symbol) {
+			return invalidGameState("stage-cleared symbol %s is not on the grid at (%d,%d)", s.Symbol, s.Position.X, s.Position.Y)
+		}
+		if seen[s.Position] {
+			return invalidGameState("stage-cleared symbol at (%d,%d) is listed twice", s.Position.X, s.Position.Y)
+		}
+		seen[s.Position] = true
+	}
+	return nil
+}
+
+// validateFreeSpins checks the game mode and the free spins counters and multiplier
+func validateFreeSpins(gameState GameState) error {
+	fs := gameState.FreeSpins
+	switch gameState.GameMode {
+	case "base":
+		if fs.Remaining != 0 {
+			return invalidGameState("freeSpins.remaining is %d outside free spins", fs.Remaining)
+		}
+	case "freeSpins":
+		if fs.TotalAwarded <= 0 || fs.TotalAwarded > FreeSpinsAwarded {
+			return invalidGameState("freeSpins.totalAwarded %d is outside 1-%d", fs.TotalAwarded, FreeSpinsAwarded)
+		}
+		if fs.Remaining <= 0 || fs.Remaining > fs.TotalAwarded {
+			return invalidGameState("freeSpins.remaining %d is outside 1-%d", fs.Remaining, fs.TotalAwarded)
+		}
+		if !slices.Contains(FreeSpinMultipliers, fs.Multiplier) {
+			return invalidGameState("freeSpins.multiplier %.2f is not an awarded multiplier", fs.Multiplier)
+		}
+	default:
+		return invalidGameState("unknown gameMode %q", gameState.GameMode)
+	}
+	return nil
+}
+
+// validateLastConnections checks that each reported connection is exactly one connected group on the grid
+func validateLastConnections(connections []Connection, grid [][]string, level Level) error {
+	groups := FindRegularConnections(grid, level)
+	groupOf := make(map[Position]int)
+	for i, group := range groups {
+		for _, pos := range group.Positions {
+			groupOf[pos] = i
+		}
+	}
+
+	used := make(map[int]bool)
+	for i, conn := range connections {
+		if conn.Count != len(conn.Positions) {
+			return invalidGameState("lastConnections[%d] has count %d but %d positions", i, conn.Count, len(conn.Positions))
+		}
+		if len(conn.Positions) == 0 {
+			return invalidGameState("lastConnections[%d] has no positions", i)
+		}
+		g, ok := groupOf[conn.Positions[0]]
+		if !ok || groups[g].Symbol != conn.Symbol || len(groups[g].Positions) != len(conn.Positions) {
+			return invalidGameState("lastConnections[%d] (%d %s) does not match a connection on the grid", i, conn.Count, conn.Symbol)
+		}
+		seen := make(map[Position]bool)
+		for _, pos := range conn.Positions {
+			if pg, ok := groupOf[pos]; !ok || pg != g || seen[pos] {
+				return invalidGameState("lastConnections[%d] position (%d,%d) is not part of the connection", i, pos.X, pos.Y)
+			}
+			seen[pos] = true
+		}
+		if used[g] {
+			return invalidGameState("lastConnections[%d] repeats an earlier connection", i)
+		}
+		used[g] = true
+	}
+	return nil
+}
+
+// onGrid reports whether pos lies within the square grid
+func onGrid(pos Position, grid [][]string) bool {
+	return pos.Y >= 0 && pos.Y < len(grid) && pos.X >= 0 && pos.X < len(grid[pos.Y])
+}
+
+// invalidGameState creates an INVALID_GAME_STATE error with a formatted reason
+func invalidGameState(format string, args ...interface{}) error {
+	return apierror.New(apierror.InvalidGameState, fmt.Sprintf(format, args...))
+}
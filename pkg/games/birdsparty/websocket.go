@@ -0,0 +1,229 @@
+package birdsparty
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/apierror"
+	"github.com/JILI-GAMES/b_backend_games8/pkg/common/money"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Locals keys carrying the resolved session from the upgrade request to the WebSocket handler
+const (
+	localsSocketEnv    = "birdsparty.socketEnv"
+	localsSocketClient = "birdsparty.socketClient"
+	localsSocketID     = "birdsparty.socketID"
+)
+
+// defaultWebSocketKeepAlive is used when RouteGroup.WebSocketKeepAlive is not set
+const defaultWebSocketKeepAlive = 25 * time.Second
+
+// tokenFromQuery lets browser WebSocket clients, which cannot set headers, pass the session token as ?token=
+func tokenFromQuery(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderAuthorization) == "" && c.Query("token") != "" {
+		c.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+c.Query("token"))
+	}
+	return c.Next()
+}
+
+// upgradeWebSocket resolves the session before the connection is upgraded,
+// so tenant and currency errors are returned as ordinary HTTP responses
+func (rg *RouteGroup) upgradeWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return apierror.Write(c, apierror.New(apierror.UpgradeRequired, ""))
+	}
+
+	env, err := rg.getClientsForRequest(c)
+	if err != nil {
+		return rejectTenant(c, "", err)
+	}
+	c.Locals(localsSocketEnv, env)
+	c.Locals(localsSocketClient, clientInfoFrom(c))
+	c.Locals(localsSocketID, apierror.CorrelationID(c))
+	return c.Next()
+}
+
+// socketConn serializes writes to a WebSocket connection and tracks its running totals
+type socketConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+	// correlationID is the upgrade request's ID; errors on the connection carry it
+	correlationID string
+
+	currency money.Currency
+	totalBet money.Amount
+	totalWin money.Amount
+}
+
+// send writes one message; it is safe to call from the keep-alive goroutine and the read loop
+func (s *socketConn) send(id, messageType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteJSON(SocketMessage{ID: id, Type: messageType, Data: payload})
+}
+
+// sendError writes an error message in the standard error envelope
+func (s *socketConn) sendError(id string, err error) error {
+	_, body := apierror.Body(err, s.correlationID)
+	log.Printf("[%s] WebSocket message %s failed: %v", s.correlationID, id, err)
+	return s.send(id, MessageError, body)
+}
+
+// sendBalance adds a step's cost and win to the running totals and pushes a balance update
+func (s *socketConn) sendBalance(cost, win float64) error {
+	s.totalBet += s.currency.RoundMajor(cost)
+	s.totalWin += s.currency.RoundMajor(win)
+	return s.send("", MessageBalance, BalanceUpdate{
+		Currency: s.currency.Code,
+		TotalBet: s.currency.ToMajor(s.totalBet),
+		TotalWin: s.currency.ToMajor(s.totalWin),
+		Net:      s.currency.ToMajor(s.totalWin - s.totalBet),
+	})
+}
+
+// WebSocketHandler handles the /ws/birdsparty channel
+// The client sends spin, cascade, processStageCleared and autoplay messages with the same bodies
+// as the REST endpoints; the server answers each one, then pushes a balance update. Autoplay rounds
+// are streamed as autoplayRound messages. Keep-alives are pushed while the connection is open.
+func (rg *RouteGroup) WebSocketHandler(conn *websocket.Conn) {
+	env, ok := conn.Locals(localsSocketEnv).(requestEnv)
+	if !ok {
+		log.Printf("WebSocket opened without a resolved session")
+		conn.Close()
+		return
+	}
+	client, _ := conn.Locals(localsSocketClient).(clientInfo)
+	correlationID, _ := conn.Locals(localsSocketID).(string)
+	socket := &socketConn{conn: conn, correlationID: correlationID, currency: env.Currency}
+
+	keepAlive := rg.WebSocketKeepAlive
+	if keepAlive <= 0 {
+		keepAlive = defaultWebSocketKeepAlive
+	}
+
+	// A client that answers neither messages nor pings for two intervals is considered gone
+	conn.SetReadDeadline(time.Now().Add(2 * keepAlive))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * keepAlive))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go rg.pushKeepAlives(socket, keepAlive, done)
+
+	log.Printf("WebSocket opened: session=%s client=%s player=%s", env.Session.SessionID, env.Session.ClientID, env.Session.PlayerID)
+	defer log.Printf("WebSocket closed: session=%s", env.Session.SessionID)
+
+	for {
+		var msg SocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("WebSocket read failed: %v", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * keepAlive))
+
+		// The token was verified at upgrade; a session that expires while the connection is open
+		// answers its next message with SESSION_EXPIRED and closes it
+		if !time.Now().Before(env.Session.Expiry()) {
+			socket.sendError(msg.ID, apierror.New(apierror.SessionExpired, ""))
+			return
+		}
+
+		if err := rg.handleSocketMessage(socket, env, client, msg); err != nil {
+			log.Printf("WebSocket write failed: %v", err)
+			return
+		}
+	}
+}
+
+// handleSocketMessage runs one client message through the shared game logic and writes the result.
+// Only write failures are returned; game errors are sent to the client.
+func (rg *RouteGroup) handleSocketMessage(socket *socketConn, env requestEnv, client clientInfo, msg SocketMessage) error {
+	var (
+		resp      interface{}
+		cost, win float64
+		err       error
+	)
+
+	switch msg.Type {
+	case MessageSpin:
+		var req SpinRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return socket.sendError(msg.ID, apierror.New(apierror.InvalidRequest, ""))
+		}
+		var r SpinResponse
+		r, err = rg.playSpin(env, req, client)
+		resp, cost, win = r, r.TotalCost, r.GameState.TotalWin
+	case MessageCascade:
+		var req CascadeRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return socket.sendError(msg.ID, apierror.New(apierror.InvalidRequest, ""))
+		}
+		var r CascadeResponse
+		r, err = rg.playCascade(env, req, client)
+		resp, cost, win = r, r.TotalCost, r.GameState.TotalWin
+	case MessageProcessStageCleared:
+		var req ProcessStageClearedRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return socket.sendError(msg.ID, apierror.New(apierror.InvalidRequest, ""))
+		}
+		var r ProcessStageClearedResponse
+		r, err = rg.playProcessStageCleared(env, req, client)
+		resp, cost, win = r, r.TotalCost, r.GameState.TotalWin
+	case MessageAutoplay:
+		var req AutoplayRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			return socket.sendError(msg.ID, apierror.New(apierror.InvalidRequest, ""))
+		}
+		// Stream each round as it settles; the final message carries the whole run
+		var r AutoplayResponse
+		r, err = rg.playAutoplay(env, req, client, func(summary AutoplayRoundSummary) error {
+			return socket.send(msg.ID, MessageAutoplayRound, summary)
+		})
+		resp, cost, win = r, r.TotalCost, r.TotalWin
+	default:
+		return socket.sendError(msg.ID, apierror.New(apierror.InvalidRequest, "Unknown message type"))
+	}
+
+	if err != nil {
+		return socket.sendError(msg.ID, err)
+	}
+	if err := socket.send(msg.ID, msg.Type, resp); err != nil {
+		return err
+	}
+	return socket.sendBalance(cost, win)
+}
+
+// pushKeepAlives sends a ping frame and a keepAlive message every interval until done is closed
+func (rg *RouteGroup) pushKeepAlives(socket *socketConn, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			socket.mu.Lock()
+			err := socket.conn.WriteControl(websocket.PingMessage, nil, now.Add(interval))
+			socket.mu.Unlock()
+			if err == nil {
+				err = socket.send("", MessageKeepAlive, KeepAlive{Time: now.Unix()})
+			}
+			if err != nil {
+				log.Printf("WebSocket keep-alive failed: %v", err)
+				return
+			}
+		}
+	}
+}